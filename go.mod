@@ -0,0 +1,3 @@
+module github.com/xthexder/json-structgen
+
+go 1.18
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xthexder/json-structgen/structgen"
+)
+
+var options structgen.Options
+var outputPath, splitDir, bundlePath, templatePath, initialisms string
+var quiet, verbose, comments, check, emitSchema bool
+
+func init() {
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	flag.CommandLine.Usage = func() {
+		// Parse errors are still printed, since only the usage text is left out.
+		if quiet {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [struct.schema.json ... | -]\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		flag.PrintDefaults()
+	}
+
+	flag.StringVar(&options.PackageName, "package", "", "Generated package name")
+	flag.StringVar(&options.StructPrefix, "prefix", "Json", "Prefix for generated structs")
+	flag.StringVar(&options.StructSuffix, "suffix", "", "Suffix for generated structs")
+	flag.StringVar(&outputPath, "o", "", "Write generated source to `file` instead of stdout")
+	flag.BoolVar(&check, "check", false, "Exit non-zero and print a diff if the -o file is not up to date, without writing it")
+	flag.StringVar(&splitDir, "split-dir", "", "Write each generated type to its own file in `dir`")
+	flag.StringVar(&bundlePath, "bundle", "", "Write the input schema to `file` with refs to other files and URLs inlined, instead of Go source")
+	flag.BoolVar(&emitSchema, "emit-schema", false, "Write the input schemas with refs and parents merged in as JSON, instead of Go source")
+	flag.StringVar(&options.BaseDir, "basedir", "", "Directory used to resolve all relative refs (default is the directory of the file containing each ref)")
+	flag.BoolVar(&options.Embed, "embed", false, "Embed titled extends and allOf parents as named types instead of copying their properties")
+	flag.BoolVar(&options.Camel, "camel", false, "Rewrite json tags of snake_case keys to lowerCamelCase")
+	flag.BoolVar(&options.OmitEmpty, "omitempty", false, "Add omitempty to tags of fields not listed as required")
+	flag.BoolVar(&options.DeepOmitEmpty, "deep-omitempty", false, "Generate MarshalJSON methods that omit zero-valued struct fields not listed as required")
+	flag.BoolVar(&options.Pointers, "pointers", false, "Use pointer types for fields not listed as required")
+	flag.BoolVar(&options.Generics, "generics", false, "Use a generic Optional[T] type instead of pointers for nullable and optional fields (requires Go 1.18)")
+	flag.BoolVar(&options.Conditionals, "conditionals", false, "Merge the properties of if/then/else branches into the parent as optional fields")
+	flag.BoolVar(&options.Accessors, "accessors", false, "Generate Get and Set methods for pointer fields")
+	flag.BoolVar(&options.CatchAll, "catch-all", false, "Collect additionalProperties of structs with properties in an AdditionalProperties map field")
+	flag.StringVar(&options.CatchAllField, "catch-all-field", "AdditionalProperties", "Go field `name` for properties collected by -catch-all, numbered if a property already has it")
+	flag.StringVar(&options.CatchAllType, "catch-all-type", "", "Go `type` of the -catch-all field: json.RawMessage or map[string]interface{} (default is a map of the additionalProperties type)")
+	flag.BoolVar(&options.LooseKeys, "loose-keys", false, "Generate UnmarshalJSON methods that also accept keys differing in case and separators, like Created-At for created_at")
+	flag.BoolVar(&options.StrictUnmarshal, "strict-unmarshal", false, "Generate UnmarshalJSON methods that reject unknown fields for additionalProperties: false")
+	flag.BoolVar(&options.MapKeys, "map-keys", false, "Key additionalProperties maps by an enum type generated from propertyNames")
+	flag.BoolVar(&options.NoCollapse, "no-collapse", false, "Inline all nested objects, so only top-level schemas become named types")
+	flag.BoolVar(&options.CollapseAll, "collapse-all", false, "Give untitled nested objects named types based on their path")
+	flag.BoolVar(&options.NamespaceTitles, "namespace-titles", false, "Prefix the name of a titled object with its parent's title when another schema already has that title")
+	flag.BoolVar(&options.Dedup, "dedup", false, "Hoist structurally identical anonymous structs into shared named types")
+	flag.BoolVar(&options.Validators, "validators", false, "Generate Validate methods from minimum, maximum, multipleOf, minLength, maxLength, minItems, maxItems, pattern and propertyNames patterns")
+	flag.BoolVar(&options.Stringer, "stringer", false, "Generate String methods for enum types")
+	flag.BoolVar(&options.Limits, "limits", false, "Generate constants for the minimum and maximum of numeric properties")
+	flag.StringVar(&options.IntType, "int-type", "int64", "Go `type` for integer schemas: int, int32, int64 or json.Number")
+	flag.StringVar(&options.NumberType, "number-type", "float64", "Go `type` for number schemas: float32, float64 or json.Number")
+	flag.StringVar(&options.UniqueItems, "unique-items", "", "Handle uniqueItems arrays as a `mode`: set (ordered element types only) or validate")
+	flag.BoolVar(&options.SplitRW, "split-rw", false, "Also generate Request and Response structs without readOnly and writeOnly properties")
+	flag.BoolVar(&options.Registry, "registry", false, "Generate a TypeRegistry map from type names without prefix and suffix to their reflect.Type")
+	flag.BoolVar(&options.DeepCopy, "deepcopy", false, "Generate DeepCopy methods for all named types")
+	flag.BoolVar(&options.Equal, "equal", false, "Generate Equal methods for all named types")
+	flag.BoolVar(&options.IsZero, "iszero", false, "Generate IsZero methods for all named types")
+	flag.BoolVar(&options.GenTests, "gen-tests", false, "Also write a _test.go file next to the output with JSON round-trip tests for each type")
+	flag.BoolVar(&options.YAMLTags, "yaml", false, "Add yaml tags alongside json tags")
+	flag.StringVar(&templatePath, "template", "", "Render each type with the text/template in `file` instead of the default")
+	flag.BoolVar(&options.NoFormat, "no-format", false, "Print the generated source without running gofmt on it")
+	flag.BoolVar(&comments, "comments", true, "Generate doc comments from descriptions")
+	flag.IntVar(&options.CommentWidth, "comment-width", 80, "Wrap comments longer than `n` characters, or 0 to never wrap")
+	flag.BoolVar(&options.NoHeader, "no-header", false, "Omit the generated code header comment")
+	flag.StringVar(&initialisms, "initialisms", "", "Comma separated list of extra initialisms to keep uppercase in names")
+	flag.BoolVar(&options.NoRemote, "no-remote", false, "Forbid fetching http and https refs")
+	flag.BoolVar(&options.PreserveOrder, "preserve-order", false, "Keep struct fields in schema declaration order instead of sorting them")
+	flag.BoolVar(&options.Strict, "strict", false, "Fail on unsupported types instead of warning and using interface{}")
+	flag.BoolVar(&quiet, "q", false, "Only print errors, without usage text or warnings")
+	flag.BoolVar(&verbose, "v", false, "Log loaded refs, registered types and interface{} fallbacks to stderr")
+}
+
+const (
+	ExitOK = iota
+	ExitUsage
+	ExitParse
+	ExitGenerate
+	ExitStale
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	if err := flag.CommandLine.Parse(os.Args[1:]); err == flag.ErrHelp {
+		return ExitOK
+	} else if err != nil {
+		return ExitUsage
+	}
+
+	options.NoComments = !comments
+	if verbose {
+		options.Log = os.Stderr
+	}
+	if len(templatePath) > 0 {
+		tmpl, err := ioutil.ReadFile(templatePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitUsage
+		}
+		options.Template = string(tmpl)
+	}
+	if len(initialisms) > 0 {
+		options.Initialisms = strings.Split(initialisms, ",")
+	}
+
+	inputs := flag.Args()
+	if len(inputs) == 0 && StdinIsPipe() {
+		inputs = []string{"-"}
+	}
+	if len(inputs) == 0 || (len(inputs) > 1 && ContainsString(inputs, "-")) {
+		if quiet {
+			fmt.Fprintln(os.Stderr, "Expected schema files or a single -")
+		} else {
+			flag.CommandLine.Usage()
+		}
+		return ExitUsage
+	}
+
+	if options.GenTests && len(outputPath) == 0 && len(splitDir) == 0 {
+		fmt.Fprintln(os.Stderr, "-gen-tests requires -o or -split-dir")
+		return ExitUsage
+	}
+
+	if options.NoCollapse && options.CollapseAll {
+		fmt.Fprintln(os.Stderr, "-no-collapse and -collapse-all can't be used together")
+		return ExitUsage
+	}
+
+	if options.LooseKeys && options.Embed {
+		// A struct would take the UnmarshalJSON method of a parent it embeds.
+		fmt.Fprintln(os.Stderr, "-loose-keys and -embed can't be used together")
+		return ExitUsage
+	}
+
+	if emitSchema && (check || len(splitDir) > 0 || options.GenTests) {
+		fmt.Fprintln(os.Stderr, "-emit-schema can't be used with -check, -split-dir or -gen-tests")
+		return ExitUsage
+	}
+
+	if len(bundlePath) > 0 && (emitSchema || check || len(splitDir) > 0 || options.GenTests || len(inputs) > 1) {
+		fmt.Fprintln(os.Stderr, "-bundle takes a single schema and can't be used with -emit-schema, -check, -split-dir or -gen-tests")
+		return ExitUsage
+	}
+
+	if check && len(outputPath) == 0 {
+		fmt.Fprintln(os.Stderr, "-check requires -o")
+		return ExitUsage
+	}
+
+	inputs, err := SchemaFiles(inputs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitParse
+	}
+
+	g := structgen.NewGenerator(options)
+	schemas := make([]*structgen.JsonSchema, len(inputs))
+	names := make([]string, len(inputs))
+	for i, input := range inputs {
+		var err error
+		if input == "-" {
+			names[i] = "stdin"
+			if schemas[i], err = structgen.Read(os.Stdin); err != nil {
+				err = fmt.Errorf("stdin: %v", err)
+			}
+		} else {
+			names[i] = filepath.Base(input)
+			if input, err = filepath.Abs(input); err == nil {
+				schemas[i], err = g.Load(input)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitParse
+		}
+	}
+	g.SourceName = strings.Join(names, ", ")
+
+	if len(bundlePath) > 0 {
+		if err := WriteBundle(g, schemas[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitGenerate
+		}
+		return ExitOK
+	}
+	if emitSchema {
+		if err := EmitSchema(g, schemas); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitGenerate
+		}
+		return ExitOK
+	}
+
+	out, err := g.Generate(schemas...)
+	for _, warning := range g.Warnings {
+		if !quiet {
+			fmt.Fprintln(os.Stderr, "Warning:", warning)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitGenerate
+	}
+
+	if check {
+		var stale bool
+		if stale, err = CheckOutput(g, out); err == nil && stale {
+			return ExitStale
+		}
+	} else if len(splitDir) > 0 {
+		err = g.WriteSplit(splitDir)
+	} else if len(outputPath) == 0 {
+		fmt.Print(out)
+	} else {
+		err = structgen.WriteFileAtomic(outputPath, []byte(out))
+		if err == nil && options.GenTests {
+			err = WriteTests(g, outputPath)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitGenerate
+	}
+	return ExitOK
+}
+
+func ContainsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaFiles replaces each directory in inputs with the *.schema.json files
+// found anywhere below it, in lexical order.
+func SchemaFiles(inputs []string) ([]string, error) {
+	var files []string
+	for _, input := range inputs {
+		if info, err := os.Stat(input); input == "-" || err != nil || !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+
+		found := len(files)
+		err := filepath.WalkDir(input, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && strings.HasSuffix(entry.Name(), ".schema.json") {
+				files = append(files, path)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == found {
+			return nil, fmt.Errorf("No *.schema.json files in %s", input)
+		}
+	}
+	return files, nil
+}
+
+func StdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// EmitSchema writes the resolved form of each schema as indented JSON to
+// outputPath or stdout. Several schemas are written as one JSON array.
+func EmitSchema(g *structgen.Generator, schemas []*structgen.JsonSchema) error {
+	resolved := make([]interface{}, len(schemas))
+	for i, schema := range schemas {
+		var err error
+		if resolved[i], err = g.ResolvedSchema(schema); err != nil {
+			return err
+		}
+	}
+	var doc interface{} = resolved
+	if len(resolved) == 1 {
+		doc = resolved[0]
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	if len(outputPath) == 0 {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return structgen.WriteFileAtomic(outputPath, out)
+}
+
+// WriteBundle writes schema to bundlePath as indented JSON, with its refs to
+// other files and URLs inlined.
+func WriteBundle(g *structgen.Generator, schema *structgen.JsonSchema) error {
+	bundle, err := g.Bundle(schema)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return structgen.WriteFileAtomic(bundlePath, append(out, '\n'))
+}
+
+func WriteTests(g *structgen.Generator, outputPath string) error {
+	tests, err := g.TestSource(g.PackageName)
+	if err != nil || tests == nil {
+		return err
+	}
+	return structgen.WriteFileAtomic(TestsPath(outputPath), tests)
+}
+
+func TestsPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, ".go") + "_test.go"
+}
+
+// CheckOutput compares the generated source, and its tests with -gen-tests,
+// to the files at outputPath and prints a diff to stderr for each one that
+// is missing or differs.
+func CheckOutput(g *structgen.Generator, out string) (bool, error) {
+	paths := []string{outputPath}
+	files := [][]byte{[]byte(out)}
+	if options.GenTests {
+		tests, err := g.TestSource(g.PackageName)
+		if err != nil {
+			return false, err
+		}
+		if tests != nil {
+			paths = append(paths, TestsPath(outputPath))
+			files = append(files, tests)
+		}
+	}
+
+	stale := false
+	for i, path := range paths {
+		existing, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		if diff := structgen.UnifiedDiff(path, path+" (generated)", string(existing), string(files[i])); len(diff) > 0 {
+			fmt.Fprint(os.Stderr, diff)
+			stale = true
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,144 @@
+package structgen
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1", "%", "%25")
+
+// Bundle returns js as a JSON value in which every $ref to another file or
+// URL is replaced by the schema it points to, so the result can be used
+// without them. Refs within js's own document are kept. The first place a
+// schema is inlined becomes its home, and later refs to it, including
+// recursive ones, point there instead.
+func (g *Generator) Bundle(js *JsonSchema) (interface{}, error) {
+	b := bundler{g, js.root, make(map[string]*JsonSchema), make(map[*JsonSchema]string)}
+	b.place(js, "")
+	return b.value(js, "", true)
+}
+
+type bundler struct {
+	g    *Generator
+	main *JsonSchema
+	docs map[string]*JsonSchema
+	// placed holds the pointer in the output that refs to each schema use:
+	// where it is for schemas of the main document, and where it was first
+	// inlined for others.
+	placed map[*JsonSchema]string
+}
+
+// value converts js, written at pointer in the output. Schemas below
+// additionalProperties and additionalItems aren't addressable, since refs
+// can't point into them.
+func (b bundler) value(js *JsonSchema, pointer string, addressable bool) (interface{}, error) {
+	if js.never {
+		return false, nil
+	}
+	if len(js.Ref) > 0 && (js.root != b.main || !strings.HasPrefix(js.Ref, "#")) {
+		return b.ref(js, pointer, addressable)
+	}
+	if _, ok := b.placed[js]; !ok && addressable {
+		b.placed[js] = pointer
+	}
+	if err := js.ParseAdditional(); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{})
+	keys, values := Keywords(js)
+	for i, key := range keys {
+		value, err := b.field(values[i], pointer+"/"+key, addressable && key != "additionalProperties" && key != "additionalItems")
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+	return out, nil
+}
+
+// place records where each schema of the main document is, so refs to them
+// keep pointing there rather than to the first place they are inlined.
+func (b bundler) place(js *JsonSchema, pointer string) {
+	b.placed[js] = pointer
+	keys, values := Keywords(js)
+	for i, key := range keys {
+		switch t := values[i].(type) {
+		case *JsonSchema:
+			if key != "additionalProperties" && key != "additionalItems" {
+				b.place(t, pointer+"/"+key)
+			}
+		case []*JsonSchema:
+			for j, schema := range t {
+				b.place(schema, pointer+"/"+key+"/"+strconv.Itoa(j))
+			}
+		case map[string]*JsonSchema:
+			for name, schema := range t {
+				b.place(schema, pointer+"/"+key+"/"+pointerEscaper.Replace(name))
+			}
+		}
+	}
+}
+
+func (b bundler) ref(js *JsonSchema, pointer string, addressable bool) (interface{}, error) {
+	path, fragment := js.Ref, ""
+	if i := strings.Index(js.Ref, "#"); i >= 0 {
+		path, fragment = js.Ref[:i], js.Ref[i+1:]
+	}
+
+	root := js.root
+	if len(path) > 0 {
+		uri := ResolveURI(js.base, path)
+		if root = b.docs[uri]; root == nil {
+			var err error
+			if root, err = b.g.RefDocument(path, js, &JsonSchema{}); err != nil {
+				return nil, err
+			}
+			b.docs[uri] = root
+		}
+	}
+	if root == nil {
+		return nil, errors.New("Ref has no root schema: " + js.Ref)
+	}
+	target := root.Resolve(fragment)
+	if target == nil {
+		return nil, errors.New("Ref not found: " + js.Ref)
+	}
+	if home, ok := b.placed[target]; ok {
+		return map[string]interface{}{"$ref": "#" + home}, nil
+	}
+
+	value, err := b.value(target, pointer, addressable)
+	if out, ok := value.(map[string]interface{}); ok && len(js.Title) > 0 && out["title"] == nil {
+		out["title"] = js.Title
+	}
+	return value, err
+}
+
+func (b bundler) field(value interface{}, pointer string, addressable bool) (interface{}, error) {
+	switch t := value.(type) {
+	case *JsonSchema:
+		return b.value(t, pointer, addressable)
+	case []*JsonSchema:
+		list := make([]interface{}, len(t))
+		for i, schema := range t {
+			var err error
+			if list[i], err = b.value(schema, pointer+"/"+strconv.Itoa(i), addressable); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]*JsonSchema:
+		// Schemas are placed in a fixed order, so refs to them are stable.
+		schemas := make(map[string]interface{}, len(t))
+		for _, key := range SortedKeys(t) {
+			var err error
+			if schemas[key], err = b.value(t[key], pointer+"/"+pointerEscaper.Replace(key), addressable); err != nil {
+				return nil, err
+			}
+		}
+		return schemas, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,214 @@
+package structgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+func (g *Generator) DeepCopyMethod(name string) string {
+	typ := g.parseType(g.types[name])
+	if typ == nil {
+		return ""
+	}
+	if typ = g.underlying(typ); g.isInterface(typ) {
+		return ""
+	}
+
+	src := "func (x *" + name + ") DeepCopy() *" + name + " {\n"
+	src += "if x == nil {\nreturn nil\n}\n"
+	src += "out := new(" + name + ")\n*out = *x\n"
+	if _, ok := typ.(*ast.StructType); ok {
+		src += g.copyFields("out", "x", typ, 0)
+	} else {
+		src += g.copyValue("*out", "*x", typ, 0)
+	}
+	return src + "return out\n}\n\n"
+}
+
+func (g *Generator) parseType(src string) ast.Expr {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return nil
+	}
+	return expr
+}
+
+// exprSource prints typ as Go source. Unlike types.ExprString it keeps the
+// tags of struct types, which are part of their identity.
+func exprSource(typ ast.Expr) string {
+	var src bytes.Buffer
+	if err := printer.Fprint(&src, token.NewFileSet(), typ); err != nil {
+		return types.ExprString(typ)
+	}
+	return src.String()
+}
+
+func (g *Generator) isInterface(typ ast.Expr) bool {
+	_, ok := typ.(*ast.InterfaceType)
+	return ok
+}
+
+// underlying resolves named types to their definition, following types
+// defined in terms of other generated types.
+func (g *Generator) underlying(typ ast.Expr) ast.Expr {
+	for i := 0; i < len(g.types); i++ {
+		ident, ok := typ.(*ast.Ident)
+		if !ok {
+			return typ
+		}
+		src, ok := g.types[ident.Name]
+		if !ok {
+			return typ
+		}
+		if typ = g.parseType(src); typ == nil {
+			return ident
+		}
+	}
+	return typ
+}
+
+func (g *Generator) needsDeepCopy(typ ast.Expr, seen map[string]bool) bool {
+	if ident, ok := typ.(*ast.Ident); ok {
+		if seen[ident.Name] {
+			return false
+		}
+		seen[ident.Name] = true
+	}
+
+	switch t := g.underlying(typ).(type) {
+	case *ast.StarExpr, *ast.MapType:
+		return true
+	case *ast.ArrayType:
+		return t.Len == nil || g.needsDeepCopy(t.Elt, seen)
+	case *ast.StructType:
+		for _, field := range t.Fields.List {
+			if g.needsDeepCopy(field.Type, seen) {
+				return true
+			}
+		}
+	case *ast.InterfaceType:
+		if ident, ok := typ.(*ast.Ident); ok {
+			for _, variant := range g.variants[ident.Name] {
+				if g.needsDeepCopy(ast.NewIdent(variant), seen) {
+					return true
+				}
+			}
+		}
+	case *ast.IndexExpr:
+		return g.needsDeepCopy(t.Index, seen)
+	case *ast.SelectorExpr:
+		return types.ExprString(t) == "json.RawMessage"
+	}
+	return false
+}
+
+func (g *Generator) copyFields(dst, src string, typ ast.Expr, depth int) string {
+	out := ""
+	for _, field := range g.underlying(typ).(*ast.StructType).Fields.List {
+		if !g.needsDeepCopy(field.Type, make(map[string]bool)) {
+			continue
+		}
+		names := []string{embeddedName(field.Type)}
+		if len(field.Names) > 0 {
+			names = names[:0]
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+		}
+		for _, name := range names {
+			out += g.copyValue(paren(dst)+"."+name, paren(src)+"."+name, field.Type, depth)
+		}
+	}
+	return out
+}
+
+// copyValue returns statements replacing the shallow copy in dst with a deep
+// copy of src.
+func (g *Generator) copyValue(dst, src string, typ ast.Expr, depth int) string {
+	if !g.needsDeepCopy(typ, make(map[string]bool)) {
+		return ""
+	}
+	typeStr := exprSource(typ)
+	if ident, ok := typ.(*ast.Ident); ok {
+		if def := g.parseType(g.types[ident.Name]); def != nil && !g.isInterface(def) {
+			return dst + " = *" + paren(src) + ".DeepCopy()\n"
+		}
+	}
+
+	switch t := g.underlying(typ).(type) {
+	case *ast.StarExpr:
+		if ident, ok := t.X.(*ast.Ident); ok {
+			if def := g.parseType(g.types[ident.Name]); def != nil && !g.isInterface(def) {
+				return dst + " = " + paren(src) + ".DeepCopy()\n"
+			}
+		}
+		out := "if " + src + " != nil {\n"
+		out += dst + " = new(" + exprSource(t.X) + ")\n"
+		out += "*" + dst + " = *" + src + "\n"
+		out += g.copyValue("*"+dst, "*"+src, t.X, depth)
+		return out + "}\n"
+	case *ast.ArrayType:
+		i := "i" + strconv.Itoa(depth)
+		loop := "for " + i + " := range " + src + " {\n"
+		loop += g.copyValue(paren(dst)+"["+i+"]", paren(src)+"["+i+"]", t.Elt, depth+1) + "}\n"
+		if t.Len != nil {
+			return loop
+		}
+		out := "if " + src + " != nil {\n"
+		out += dst + " = make(" + typeStr + ", len(" + src + "))\n"
+		out += "copy(" + dst + ", " + src + ")\n"
+		if g.needsDeepCopy(t.Elt, make(map[string]bool)) {
+			out += loop
+		}
+		return out + "}\n"
+	case *ast.MapType:
+		k, v := "k"+strconv.Itoa(depth), "v"+strconv.Itoa(depth)
+		out := "if " + src + " != nil {\n"
+		out += dst + " = make(" + typeStr + ", len(" + src + "))\n"
+		out += "for " + k + ", " + v + " := range " + src + " {\n"
+		if g.needsDeepCopy(t.Value, make(map[string]bool)) {
+			c := "c" + strconv.Itoa(depth)
+			out += c + " := " + v + "\n"
+			out += g.copyValue(c, v, t.Value, depth+1)
+			v = c
+		}
+		out += paren(dst) + "[" + k + "] = " + v + "\n}\n"
+		return out + "}\n"
+	case *ast.StructType:
+		return g.copyFields(dst, src, t, depth)
+	case *ast.InterfaceType:
+		v := "v" + strconv.Itoa(depth)
+		out := "switch " + v + " := " + paren(src) + ".(type) {\n"
+		for _, variant := range g.variants[typeStr] {
+			if g.needsDeepCopy(ast.NewIdent(variant), make(map[string]bool)) {
+				out += "case " + variant + ":\n"
+				out += dst + " = *" + v + ".DeepCopy()\n"
+			}
+		}
+		return out + "}\n"
+	case *ast.IndexExpr:
+		return g.copyValue(paren(dst)+".Value", paren(src)+".Value", t.Index, depth)
+	case *ast.SelectorExpr:
+		return dst + " = append(" + typeStr + "(nil), " + src + "...)\n"
+	}
+	return ""
+}
+
+func embeddedName(typ ast.Expr) string {
+	name := types.ExprString(typ)
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimPrefix(name, "*")
+}
+
+func paren(expr string) string {
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
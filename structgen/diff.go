@@ -0,0 +1,156 @@
+package structgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxDiffTable bounds the size of the table used to find the longest common
+// subsequence; larger differences are shown as one replaced block.
+const maxDiffTable = 1 << 22
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// UnifiedDiff returns a unified diff that turns a into b, or "" if they are
+// equal.
+func UnifiedDiff(nameA, nameB, a, b string) string {
+	if a == b {
+		return ""
+	}
+	lines := DiffLines(SplitLines(a), SplitLines(b))
+
+	// posA[i] and posB[i] count the lines of a and b before lines[i].
+	posA, posB := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, line := range lines {
+		posA[i+1], posB[i+1] = posA[i], posB[i]
+		if line.op != '+' {
+			posA[i+1]++
+		}
+		if line.op != '-' {
+			posB[i+1]++
+		}
+	}
+
+	out := "--- " + nameA + "\n+++ " + nameB + "\n"
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		start, end := i-diffContext, i
+		if start < 0 {
+			start = 0
+		}
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+			if next == len(lines) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		stop := end + diffContext
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		out += "@@ -" + HunkRange(posA[start], posA[stop]-posA[start]) +
+			" +" + HunkRange(posB[start], posB[stop]-posB[start]) + " @@\n"
+		for _, line := range lines[start:stop] {
+			out += string(line.op) + line.text
+			if !strings.HasSuffix(line.text, "\n") {
+				out += "\n\\ No newline at end of file\n"
+			}
+		}
+		i = stop
+	}
+	return out
+}
+
+// HunkRange formats the start and length of a hunk, where start counts the
+// lines before it.
+func HunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// SplitLines splits s after each newline. A final line without one is kept.
+func SplitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func DiffLines(a, b []string) []diffLine {
+	var prefix, suffix []diffLine
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, diffLine{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]diffLine{{' ', a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	lines := prefix
+	if (len(a)+1)*(len(b)+1) > maxDiffTable {
+		for _, line := range a {
+			lines = append(lines, diffLine{'-', line})
+		}
+		for _, line := range b {
+			lines = append(lines, diffLine{'+', line})
+		}
+		return append(lines, suffix...)
+	}
+
+	// lcs[i*(m+1)+j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	n, m := len(a), len(b)
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else if down, right := lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1]; down >= right {
+				lcs[i*(m+1)+j] = down
+			} else {
+				lcs[i*(m+1)+j] = right
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case j == m || i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return append(lines, suffix...)
+}
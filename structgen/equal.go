@@ -0,0 +1,144 @@
+package structgen
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+)
+
+func (g *Generator) EqualMethod(name string) string {
+	typ := g.parseType(g.types[name])
+	if typ == nil {
+		return ""
+	}
+	if typ = g.underlying(typ); g.isInterface(typ) {
+		return ""
+	}
+
+	src := "func (a " + name + ") Equal(b " + name + ") bool {\n"
+	if isComparable(typ) {
+		return src + "return a == b\n}\n\n"
+	}
+	src += g.equalValue("a", "b", typ, 0)
+	return src + "return true\n}\n\n"
+}
+
+// isComparable reports whether typ is a predeclared type whose values can
+// be compared with ==. Other idents name types declared next to the
+// generated code, which may hold slices or maps.
+func isComparable(typ ast.Expr) bool {
+	ident, ok := typ.(*ast.Ident)
+	return ok && (numericIdents[ident.Name] || ident.Name == "string" || ident.Name == "bool")
+}
+
+// hasMethods reports whether typ is a generated type that gets its own Equal
+// and IsZero methods.
+func (g *Generator) hasMethods(typ ast.Expr) bool {
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	def := g.parseType(g.types[ident.Name])
+	return def != nil && !g.isInterface(g.underlying(def))
+}
+
+// equalValue returns statements that return false unless a and b hold equal
+// values of typ.
+func (g *Generator) equalValue(a, b string, typ ast.Expr, depth int) string {
+	typeStr := types.ExprString(typ)
+	underlying := g.underlying(typ)
+	if isComparable(underlying) {
+		return "if " + a + " != " + b + " {\nreturn false\n}\n"
+	}
+	if g.hasMethods(typ) {
+		return "if !" + paren(a) + ".Equal(" + b + ") {\nreturn false\n}\n"
+	}
+
+	switch t := underlying.(type) {
+	case *ast.StarExpr:
+		out := "if (" + a + " == nil) != (" + b + " == nil) {\nreturn false\n}\n"
+		if isComparable(g.underlying(t.X)) {
+			return out + "if " + a + " != nil && *" + a + " != *" + b + " {\nreturn false\n}\n"
+		}
+		if g.hasMethods(t.X) || types.ExprString(t.X) == "time.Time" {
+			return out + "if " + a + " != nil && !" + paren(a) + ".Equal(*" + b + ") {\nreturn false\n}\n"
+		}
+		return out + "if " + a + " != nil {\n" + g.equalValue("*"+a, "*"+b, t.X, depth) + "}\n"
+	case *ast.ArrayType:
+		i := "i" + strconv.Itoa(depth)
+		out := ""
+		if t.Len == nil {
+			out = "if (" + a + " == nil) != (" + b + " == nil) || len(" + a + ") != len(" + b + ") {\nreturn false\n}\n"
+		}
+		if elem := g.equalValue(paren(a)+"["+i+"]", paren(b)+"["+i+"]", t.Elt, depth+1); len(elem) > 0 {
+			out += "for " + i + " := range " + a + " {\n" + elem + "}\n"
+		}
+		return out
+	case *ast.MapType:
+		k, v, w := "k"+strconv.Itoa(depth), "v"+strconv.Itoa(depth), "w"+strconv.Itoa(depth)
+		out := "if (" + a + " == nil) != (" + b + " == nil) || len(" + a + ") != len(" + b + ") {\nreturn false\n}\n"
+		value := g.equalValue(v, w, t.Value, depth+1)
+		if len(value) == 0 {
+			out += "for " + k + " := range " + a + " {\n"
+			out += "if _, ok := " + paren(b) + "[" + k + "]; !ok {\nreturn false\n}\n"
+			return out + "}\n"
+		}
+		out += "for " + k + ", " + v + " := range " + a + " {\n"
+		out += w + ", ok := " + paren(b) + "[" + k + "]\n"
+		out += "if !ok {\nreturn false\n}\n"
+		return out + value + "}\n"
+	case *ast.StructType:
+		out := ""
+		for _, field := range t.Fields.List {
+			names := []string{embeddedName(field.Type)}
+			if len(field.Names) > 0 {
+				names = names[:0]
+				for _, name := range field.Names {
+					names = append(names, name.Name)
+				}
+			}
+			for _, name := range names {
+				out += g.equalValue(paren(a)+"."+name, paren(b)+"."+name, field.Type, depth)
+			}
+		}
+		return out
+	case *ast.InterfaceType:
+		variants := g.variants[typeStr]
+		if len(variants) == 0 {
+			g.imports["reflect"] = true
+			return "if !reflect.DeepEqual(" + a + ", " + b + ") {\nreturn false\n}\n"
+		}
+		v, w := "v"+strconv.Itoa(depth), "w"+strconv.Itoa(depth)
+		out := "switch " + v + " := " + paren(a) + ".(type) {\n"
+		for _, variant := range variants {
+			out += "case " + variant + ":\n"
+			out += w + ", ok := " + paren(b) + ".(" + variant + ")\n"
+			out += "if !ok {\nreturn false\n}\n"
+			out += g.equalValue(v, w, ast.NewIdent(variant), depth+1)
+		}
+		out += "default:\nif " + a + " != nil || " + b + " != nil {\nreturn false\n}\n"
+		return out + "}\n"
+	case *ast.IndexExpr:
+		out := "if " + paren(a) + ".Valid != " + paren(b) + ".Valid {\nreturn false\n}\n"
+		value := g.equalValue(paren(a)+".Value", paren(b)+".Value", t.Index, depth)
+		return out + "if " + paren(a) + ".Valid {\n" + value + "}\n"
+	case *ast.SelectorExpr:
+		switch types.ExprString(t) {
+		case "json.RawMessage":
+			g.imports["bytes"] = true
+			return "if !bytes.Equal(" + a + ", " + b + ") {\nreturn false\n}\n"
+		case "json.Number":
+			return "if " + a + " != " + b + " {\nreturn false\n}\n"
+		case "time.Time":
+			return "if !" + paren(a) + ".Equal(" + b + ") {\nreturn false\n}\n"
+		}
+		// Types from other packages may not be comparable with ==.
+		g.imports["reflect"] = true
+		return "if !reflect.DeepEqual(" + a + ", " + b + ") {\nreturn false\n}\n"
+	case *ast.Ident:
+		// Neither may types declared next to the generated code.
+		g.imports["reflect"] = true
+		return "if !reflect.DeepEqual(" + a + ", " + b + ") {\nreturn false\n}\n"
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package structgen
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+)
+
+func (g *Generator) IsZeroMethod(name string) string {
+	typ := g.parseType(g.types[name])
+	if typ == nil {
+		return ""
+	}
+	if typ = g.underlying(typ); g.isInterface(typ) {
+		return ""
+	}
+
+	src := "func (x " + name + ") IsZero() bool {\n"
+	if zero, ok := zeroLiteral(typ); ok && zero == "false" {
+		return src + "return !bool(x)\n}\n\n"
+	} else if ok {
+		return src + "return x == " + zero + "\n}\n\n"
+	}
+	src += g.zeroValue("x", typ, 0)
+	return src + "return true\n}\n\n"
+}
+
+// numericIdents holds the predeclared numeric types.
+var numericIdents = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+	"byte": true, "rune": true,
+}
+
+// zeroLiteral returns the literal that values of typ are compared to, if
+// they can be compared with ==.
+func zeroLiteral(typ ast.Expr) (string, bool) {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return `""`, true
+		case "bool":
+			return "false", true
+		case "error", "any":
+			return "nil", true
+		}
+		if numericIdents[t.Name] {
+			return "0", true
+		}
+	case *ast.StarExpr, *ast.MapType, *ast.InterfaceType:
+		return "nil", true
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "nil", true
+		}
+	case *ast.SelectorExpr:
+		switch types.ExprString(t) {
+		case "json.RawMessage":
+			return "nil", true
+		case "json.Number":
+			return `""`, true
+		case "time.Duration":
+			return "0", true
+		}
+	}
+	return "", false
+}
+
+// zeroValue returns statements that return false unless v is the zero value
+// of typ.
+func (g *Generator) zeroValue(v string, typ ast.Expr, depth int) string {
+	if g.hasMethods(typ) {
+		if _, ok := zeroLiteral(g.underlying(typ)); !ok {
+			return "if !" + paren(v) + ".IsZero() {\nreturn false\n}\n"
+		}
+	}
+
+	typ = g.underlying(typ)
+	if zero, ok := zeroLiteral(typ); ok && zero == "false" {
+		return "if " + v + " {\nreturn false\n}\n"
+	} else if ok {
+		return "if " + v + " != " + zero + " {\nreturn false\n}\n"
+	}
+	switch t := typ.(type) {
+	case *ast.ArrayType:
+		i := "i" + strconv.Itoa(depth)
+		if elem := g.zeroValue(paren(v)+"["+i+"]", t.Elt, depth+1); len(elem) > 0 {
+			return "for " + i + " := range " + v + " {\n" + elem + "}\n"
+		}
+	case *ast.StructType:
+		out := ""
+		for _, field := range t.Fields.List {
+			names := []string{embeddedName(field.Type)}
+			if len(field.Names) > 0 {
+				names = names[:0]
+				for _, name := range field.Names {
+					names = append(names, name.Name)
+				}
+			}
+			for _, name := range names {
+				out += g.zeroValue(paren(v)+"."+name, field.Type, depth)
+			}
+		}
+		return out
+	case *ast.IndexExpr:
+		out := "if " + paren(v) + ".Valid {\nreturn false\n}\n"
+		return out + g.zeroValue(paren(v)+".Value", t.Index, depth)
+	case *ast.SelectorExpr:
+		if types.ExprString(t) == "time.Time" {
+			return "if !" + paren(v) + ".IsZero() {\nreturn false\n}\n"
+		}
+		// Types from other packages may not be comparable with ==. Going
+		// through a pointer keeps nil interfaces from panicking.
+		g.imports["reflect"] = true
+		return "if !reflect.ValueOf(&" + paren(v) + ").Elem().IsZero() {\nreturn false\n}\n"
+	case *ast.Ident:
+		// Neither do types declared next to the generated code.
+		g.imports["reflect"] = true
+		return "if !reflect.ValueOf(&" + paren(v) + ").Elem().IsZero() {\nreturn false\n}\n"
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package structgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strconv"
+)
+
+// maxProtoField is the largest field number protobuf allows.
+const maxProtoField = 1<<29 - 1
+
+// ProtoTags returns the gogoproto struct tags of a field of typ that has
+// number from x-proto-field and is named key in the message.
+func (g *Generator) ProtoTags(key string, number int, typ string) ([]string, error) {
+	if number < 1 || number > maxProtoField || number >= 19000 && number <= 19999 {
+		return nil, fmt.Errorf("Invalid x-proto-field %d for %s: expected 1 to %d, except 19000 to 19999", number, key, maxProtoField)
+	}
+	expr := g.parseType(typ)
+	if expr == nil {
+		return nil, fmt.Errorf("Can't parse type %s of %s", typ, key)
+	}
+	field := ",name=" + key + ",proto3"
+
+	switch t := g.underlying(g.protoElem(expr)).(type) {
+	case *ast.ArrayType:
+		if types.ExprString(t) == "json.RawMessage" {
+			break
+		}
+		wire := g.protoWireType(t.Elt)
+		if wire != "bytes" {
+			return []string{"protobuf:" + strconv.Quote(wire+","+strconv.Itoa(number)+",rep,packed"+field)}, nil
+		}
+		return []string{"protobuf:" + strconv.Quote(wire+","+strconv.Itoa(number)+",rep"+field)}, nil
+	case *ast.MapType:
+		return []string{
+			"protobuf:" + strconv.Quote("bytes,"+strconv.Itoa(number)+",rep"+field),
+			"protobuf_key:" + strconv.Quote(g.protoWireType(t.Key)+",1,opt,name=key,proto3"),
+			"protobuf_val:" + strconv.Quote(g.protoWireType(t.Value)+",2,opt,name=value,proto3"),
+		}, nil
+	case *ast.SelectorExpr:
+		// gogoproto maps well-known types to time types with these options.
+		switch types.ExprString(t) {
+		case "time.Time":
+			field += ",stdtime"
+		case "time.Duration":
+			field += ",stdduration"
+		}
+	}
+	return []string{"protobuf:" + strconv.Quote(g.protoWireType(expr)+","+strconv.Itoa(number)+",opt"+field)}, nil
+}
+
+// protoElem returns the type held by a pointer or Optional.
+func (g *Generator) protoElem(typ ast.Expr) ast.Expr {
+	switch t := typ.(type) {
+	case *ast.StarExpr:
+		return t.X
+	case *ast.IndexExpr:
+		return t.Index
+	}
+	return typ
+}
+
+// protoWireType returns the protobuf wire type used to encode values of typ.
+// Anything that isn't a number or bool is length-delimited.
+func (g *Generator) protoWireType(typ ast.Expr) string {
+	if ident, ok := g.underlying(g.protoElem(typ)).(*ast.Ident); ok {
+		switch ident.Name {
+		case "bool", "int", "int32", "int64", "uint", "uint32", "uint64":
+			return "varint"
+		case "float64":
+			return "fixed64"
+		case "float32":
+			return "fixed32"
+		}
+	}
+	return "bytes"
+}
@@ -0,0 +1,174 @@
+package structgen
+
+import (
+	"reflect"
+	"strings"
+)
+
+type expandingSchema struct {
+	schema *JsonSchema
+	key    refKey
+	ref    string
+}
+
+// ResolvedSchema returns js as a plain JSON value after loading every $ref
+// and merging extends and allOf parents into their children, which shows what
+// the generator understood from a schema spread over several files. A $ref
+// back to a schema that is still being expanded is kept, so recursive schemas
+// stay finite.
+func (g *Generator) ResolvedSchema(js *JsonSchema) (interface{}, error) {
+	r := schemaResolver{g, make(map[refKey]interface{})}
+	return r.value(js, nil)
+}
+
+// schemaResolver expands each ref once, so schemas that refer to the same
+// definition many times don't take exponential time.
+type schemaResolver struct {
+	g        *Generator
+	resolved map[refKey]interface{}
+}
+
+func (r schemaResolver) value(js *JsonSchema, expanding []expandingSchema) (interface{}, error) {
+	entry := expandingSchema{schema: js, key: js.RefKey(), ref: js.Ref}
+	var target *JsonSchema
+	if strings.HasPrefix(js.Ref, "#") && js.root != nil {
+		target = js.root.Resolve(js.Ref[1:])
+	}
+	for _, active := range expanding {
+		if target != nil && active.schema == target || len(entry.key.ref) > 0 && active.key == entry.key {
+			return map[string]interface{}{"$ref": js.Ref}, nil
+		}
+		if active.schema == js {
+			// Schemas loaded from a ref share children with their target.
+			if len(active.ref) == 0 {
+				return map[string]interface{}{}, nil
+			}
+			return map[string]interface{}{"$ref": active.ref}, nil
+		}
+	}
+	if value, ok := r.resolved[entry.key]; ok && len(entry.key.ref) > 0 {
+		return value, nil
+	}
+	top := len(expanding) == 0
+	expanding = append(expanding[:len(expanding):len(expanding)], entry)
+
+	if err := r.g.LoadRef(js); err != nil {
+		return nil, err
+	}
+	if js.never {
+		return false, nil
+	}
+
+	out := make(map[string]interface{})
+	keys, values := Keywords(js)
+	for i, key := range keys {
+		value := values[i]
+		switch key {
+		case "definitions", "$defs":
+			// Copies of a document loaded from a ref repeat its definitions.
+			if !top {
+				continue
+			}
+		case "extends":
+			// Parents that were merged into js are left out; embedded ones
+			// stay.
+			if !r.g.IsEmbeddable(js.Extends) {
+				continue
+			}
+		case "allOf":
+			var parents []*JsonSchema
+			for _, member := range js.AllOf {
+				if r.g.IsEmbeddable(member) {
+					parents = append(parents, member)
+				}
+			}
+			if len(parents) == 0 {
+				continue
+			}
+			value = parents
+		}
+		resolved, err := r.field(value, expanding)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = resolved
+	}
+	if len(entry.key.ref) > 0 {
+		r.resolved[entry.key] = out
+	}
+	return out, nil
+}
+
+// Keywords returns the keywords set in js and their values, in the order of
+// the JsonSchema fields. Values holding schemas are left as *JsonSchema,
+// []*JsonSchema or map[string]*JsonSchema for the caller to convert.
+func Keywords(js *JsonSchema) (keys []string, values []interface{}) {
+	v := reflect.ValueOf(js).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		field := v.Field(i)
+		if len(key) == 0 || key == "-" || field.IsZero() {
+			continue
+		}
+		if (field.Kind() == reflect.Map || field.Kind() == reflect.Slice) && field.Len() == 0 {
+			continue
+		}
+		switch key {
+		case "type":
+			if js.Type == "any" {
+				continue
+			}
+		case "additionalProperties":
+			if _, ok := js.AdditionalInterface.(bool); !ok {
+				if js.AdditionalProperties == nil {
+					continue
+				}
+				keys, values = append(keys, key), append(values, js.AdditionalProperties)
+				continue
+			}
+		case "additionalItems":
+			if _, ok := js.AdditionalItemsValue.(bool); !ok {
+				if js.AdditionalItems == nil {
+					continue
+				}
+				keys, values = append(keys, key), append(values, js.AdditionalItems)
+				continue
+			}
+		}
+		keys, values = append(keys, key), append(values, field.Interface())
+	}
+	if len(js.ItemsList) > 0 {
+		keys, values = append(keys, "items"), append(values, js.ItemsList)
+	} else if js.Items != nil {
+		keys, values = append(keys, "items"), append(values, js.Items)
+	}
+	return
+}
+
+func (r schemaResolver) field(value interface{}, expanding []expandingSchema) (interface{}, error) {
+	switch t := value.(type) {
+	case *JsonSchema:
+		return r.value(t, expanding)
+	case []*JsonSchema:
+		list := make([]interface{}, len(t))
+		for i, schema := range t {
+			var err error
+			if list[i], err = r.value(schema, expanding); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]*JsonSchema:
+		// Loading a ref can change schemas shared with other keys, so they
+		// are visited in a fixed order.
+		schemas := make(map[string]interface{}, len(t))
+		for _, key := range SortedKeys(t) {
+			var err error
+			if schemas[key], err = r.value(t[key], expanding); err != nil {
+				return nil, err
+			}
+		}
+		return schemas, nil
+	}
+	return value, nil
+}
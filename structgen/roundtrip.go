@@ -0,0 +1,166 @@
+package structgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// TestSource returns a test file for package pkg with a round-trip test for
+// each generated type, or nil if no type can be decoded into directly.
+func (g *Generator) TestSource(pkg string) ([]byte, error) {
+	var tests bytes.Buffer
+	for _, name := range SortedKeys(g.types) {
+		typ := g.parseType(g.types[name])
+		if typ == nil || g.isInterface(g.underlying(typ)) {
+			continue
+		}
+		fmt.Fprint(&tests, g.RoundTripTest(name))
+	}
+	if tests.Len() == 0 {
+		return nil, nil
+	}
+
+	src := g.FileHeader(pkg, []string{"encoding/json", "reflect", "testing"})
+	return g.FormatSource(append([]byte(src), tests.Bytes()...))
+}
+
+func (g *Generator) RoundTripTest(name string) string {
+	inputs := "string(zero)"
+	for _, example := range g.examples[name] {
+		inputs += ", " + strconv.Quote(example)
+	}
+
+	src := "func Test" + name + "RoundTrip(t *testing.T) {\n"
+	for _, example := range g.mismatched[name] {
+		src += "// The example " + example + " is left out, since it doesn't match the schema.\n"
+	}
+	src += "var zeroValue " + name + "\n"
+	src += "zero, err := json.Marshal(zeroValue)\n"
+	src += "if err != nil {\nt.Fatal(err)\n}\n"
+	src += "for _, data := range []string{" + inputs + "} {\n"
+	src += "var x, y " + name + "\n"
+	src += "if err := json.Unmarshal([]byte(data), &x); err != nil {\n"
+	src += "t.Errorf(\"Unmarshal %s: %v\", data, err)\ncontinue\n}\n"
+	src += "out, err := json.Marshal(x)\n"
+	src += "if err != nil {\nt.Errorf(\"Marshal %s: %v\", data, err)\ncontinue\n}\n"
+	src += "if err := json.Unmarshal(out, &y); err != nil {\n"
+	src += "t.Errorf(\"Unmarshal %s: %v\", out, err)\n"
+	src += "} else if !reflect.DeepEqual(x, y) {\n"
+	src += "t.Errorf(\"Round trip of %s: got %+v, want %+v\", data, y, x)\n}\n"
+	src += "}\n}\n\n"
+	return src
+}
+
+// RoundTripExamples returns the examples of js as JSON, split into the ones
+// that can be decoded into its type and the ones that don't match js.
+func (g *Generator) RoundTripExamples(js *JsonSchema) (matching, mismatched []string) {
+	examples := js.Examples
+	if js.Example != nil {
+		examples = append(examples[:len(examples):len(examples)], js.Example)
+	}
+	for _, example := range examples {
+		data, err := json.Marshal(example)
+		if err != nil {
+			continue
+		}
+		if g.ExampleMatches(js, example) {
+			matching = append(matching, string(data))
+		} else {
+			mismatched = append(mismatched, string(data))
+		}
+	}
+	return
+}
+
+// ExampleMatches reports whether the JSON types in value are the ones js and
+// its properties and items declare. Schemas whose types depend on more than
+// that, like oneOf and x-go-type, match anything.
+func (g *Generator) ExampleMatches(js *JsonSchema, value interface{}) bool {
+	if value == nil || len(js.OneOf) > 0 || len(js.CustomType) > 0 {
+		return true
+	}
+	switch t := js.Type.(type) {
+	case string:
+		if !js.matchesType(t, value) {
+			return false
+		}
+	case []interface{}:
+		matched := false
+		for _, v := range t {
+			if name, ok := v.(string); ok && js.matchesType(name, value) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			prop, ok := js.Properties[key]
+			if !ok && len(js.PatternProperties) == 0 {
+				// Strict types reject keys that aren't properties.
+				if g.StrictUnmarshal && js.IsClosed() {
+					return false
+				}
+				prop = js.AdditionalProperties
+			}
+			if prop != nil && !g.ExampleMatches(prop, item) {
+				return false
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			items := js.Items
+			if i < len(js.ItemsList) {
+				items = js.ItemsList[i]
+			} else if len(js.ItemsList) > 0 {
+				items = js.AdditionalItems
+			}
+			if items != nil && !g.ExampleMatches(items, item) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (js *JsonSchema) matchesType(name string, value interface{}) bool {
+	switch name {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return false
+		}
+		switch js.Format {
+		case "date-time", "date":
+			_, err := time.Parse(time.RFC3339, str)
+			return err == nil
+		case "duration":
+			return false
+		}
+		return true
+	case "integer":
+		num, ok := value.(float64)
+		return ok && num == math.Trunc(num)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package structgen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+func (g *Generator) WriteSplit(dir string) error {
+	pkg := g.PackageName
+	if len(pkg) == 0 {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		pkg = strings.ToLower(ConstName(filepath.Base(abs)))
+		if len(pkg) == 0 || unicode.IsDigit(rune(pkg[0])) {
+			pkg = "models" + pkg
+		}
+	}
+
+	files := make(map[string]string)
+	if patterns := g.PatternVars(); len(patterns) > 0 {
+		files["constants.go"] = patterns
+	}
+	if registry := g.RegistryVar(); len(registry) > 0 {
+		files["registry.go"] = registry
+	}
+	if optional := g.OptionalSource(); len(optional) > 0 {
+		files["optional.go"] = optional
+	}
+	for _, name := range SortedKeys(g.types) {
+		file := SnakeCase(name) + ".go"
+		if _, ok := g.consts[name]; ok && !g.IsStruct(name) {
+			file = "constants.go"
+		}
+		src, err := g.TypeSource(name)
+		if err != nil {
+			return err
+		}
+		files[file] += src
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, file := range SortedKeys(files) {
+		src := g.FileHeader(pkg, g.UsedImports(files[file])) + files[file]
+		srcFmt, err := g.FormatSource([]byte(src))
+		if err != nil {
+			return err
+		}
+		if err = WriteFileAtomic(filepath.Join(dir, file), srcFmt); err != nil {
+			return err
+		}
+	}
+
+	if g.GenTests {
+		tests, err := g.TestSource(pkg)
+		if err != nil || tests == nil {
+			return err
+		}
+		return WriteFileAtomic(filepath.Join(dir, "roundtrip_test.go"), tests)
+	}
+	return nil
+}
+
+func (g *Generator) UsedImports(src string) []string {
+	imports := SortedKeys(g.imports)
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+src, 0)
+	if err != nil {
+		return imports
+	}
+
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	var out []string
+	for _, imp := range imports {
+		if used[ImportName(imp)] {
+			out = append(out, imp)
+		}
+	}
+	return out
+}
+
+var importVersion = regexp.MustCompile(`^v[0-9]+$|\.v[0-9]+$`)
+
+func ImportName(imp string) string {
+	name := path.Base(imp)
+	if importVersion.MatchString(name) && strings.HasPrefix(name, "v") {
+		name = path.Base(path.Dir(imp))
+	}
+	name = importVersion.ReplaceAllString(name, "")
+	return strings.TrimPrefix(name, "go-")
+}
+
+func SnakeCase(in string) string {
+	runes := []rune(in)
+	var out []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				out = append(out, '_')
+			}
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
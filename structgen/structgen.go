@@ -0,0 +1,2652 @@
+package structgen
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Generator struct {
+	Options
+	Warnings []string
+
+	types       map[string]string
+	consts      map[string]string
+	imports     map[string]bool
+	methods     map[string]string
+	ownMethods  map[string]string
+	docs        map[string]string
+	inProgress  map[string]bool
+	anon        map[string]*AnonType
+	memo        map[memoKey]string
+	fields      map[*JsonSchema][]structField
+	structs     map[string][]FieldModel
+	variants    map[string][]string
+	schemas     map[string]*JsonSchema
+	examples    map[string][]string
+	mismatched  map[string][]string
+	loading     []refKey
+	active      []activeSchema
+	recursions  int
+	recursive   map[string]bool
+	cuts        []string
+	validations map[string][]string
+	inline      map[*JsonSchema][]string
+	patterns    map[string]string
+	remoteCache map[string][]byte
+	initialisms map[string]bool
+	template    *template.Template
+	optional    bool
+}
+
+type structField struct {
+	key, name, typ string
+}
+
+type refKey struct {
+	doc *JsonSchema
+	ref string
+}
+
+type activeSchema struct {
+	schema *JsonSchema
+	ref    refKey
+	named  bool
+}
+
+type memoKey struct {
+	schema   *JsonSchema
+	collapse bool
+}
+
+type AnonType struct {
+	ID   int
+	Name string
+	Uses map[*JsonSchema]bool
+}
+
+type JsonSchema struct {
+	Schema string `json:"$schema"`
+	ID     string `json:"$id"`
+	Ref    string `json:"$ref"`
+
+	Title                string                 `json:"title"`
+	Type                 interface{}            `json:"type"`
+	Description          string                 `json:"description"`
+	Format               string                 `json:"format"`
+	Extends              *JsonSchema            `json:"extends"`
+	Properties           map[string]*JsonSchema `json:"properties"`
+	PropertyOrder        []string               `json:"-"`
+	PatternProperties    map[string]*JsonSchema `json:"patternProperties"`
+	PropertyNames        *JsonSchema            `json:"propertyNames"`
+	AdditionalInterface  interface{}            `json:"additionalProperties"`
+	AdditionalProperties *JsonSchema            `json:"-"`
+	AdditionalItemsValue interface{}            `json:"additionalItems"`
+	AdditionalItems      *JsonSchema            `json:"-"`
+	Items                *JsonSchema            `json:"-"`
+	ItemsList            []*JsonSchema          `json:"-"`
+	UniqueItems          bool                   `json:"uniqueItems"`
+	Nullable             bool                   `json:"nullable"`
+	ReadOnly             bool                   `json:"readOnly"`
+	WriteOnly            bool                   `json:"writeOnly"`
+	Deprecated           bool                   `json:"deprecated"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MultipleOf           *float64               `json:"multipleOf"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	Contains             *JsonSchema            `json:"contains"`
+	MinContains          *int                   `json:"minContains"`
+	MaxContains          *int                   `json:"maxContains"`
+	Pattern              string                 `json:"pattern"`
+	Required             []string               `json:"required"`
+	Definitions          map[string]*JsonSchema `json:"definitions"`
+	Defs                 map[string]*JsonSchema `json:"$defs"`
+	Enum                 []interface{}          `json:"enum"`
+	Const                interface{}            `json:"const"`
+	Default              interface{}            `json:"default"`
+	Examples             []interface{}          `json:"examples"`
+	Example              interface{}            `json:"example"`
+	OneOf                []*JsonSchema          `json:"oneOf"`
+	AllOf                []*JsonSchema          `json:"allOf"`
+	AnyOf                []*JsonSchema          `json:"anyOf"`
+	If                   *JsonSchema            `json:"if"`
+	Then                 *JsonSchema            `json:"then"`
+	Else                 *JsonSchema            `json:"else"`
+	GoTags               string                 `json:"x-go-tags"`
+	CustomType           string                 `json:"x-go-type"`
+	GoName               string                 `json:"x-go-name"`
+	ProtoField           *int                   `json:"x-proto-field"`
+
+	root         *JsonSchema
+	base         string
+	path         string
+	origin       *JsonSchema
+	never        bool
+	alternatives map[string][]*JsonSchema
+	conditional  map[string]bool
+}
+
+func (js *JsonSchema) UnmarshalJSON(data []byte) error {
+	// true accepts any value, while false accepts none.
+	if value := string(bytes.TrimSpace(data)); value == "true" || value == "false" {
+		*js = JsonSchema{Type: "any", never: value == "false"}
+		return nil
+	}
+
+	type plain JsonSchema
+	if err := json.Unmarshal(data, (*plain)(js)); err != nil {
+		return err
+	}
+
+	if js.MultipleOf != nil && *js.MultipleOf <= 0 {
+		return fmt.Errorf("Invalid multipleOf: %v", *js.MultipleOf)
+	}
+
+	var raw struct {
+		Properties           json.RawMessage `json:"properties"`
+		Items                json.RawMessage `json:"items"`
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+		AdditionalItems      json.RawMessage `json:"additionalItems"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	// Boolean values stay in AdditionalInterface and AdditionalItemsValue.
+	if value := bytes.TrimSpace(raw.AdditionalProperties); len(value) > 0 && value[0] == '{' {
+		if err := json.Unmarshal(value, &js.AdditionalProperties); err != nil {
+			return err
+		}
+	}
+	if value := bytes.TrimSpace(raw.AdditionalItems); len(value) > 0 && value[0] == '{' {
+		if err := json.Unmarshal(value, &js.AdditionalItems); err != nil {
+			return err
+		}
+	}
+	if items := bytes.TrimSpace(raw.Items); len(items) > 0 && items[0] == '[' {
+		if err := json.Unmarshal(items, &js.ItemsList); err != nil {
+			return err
+		}
+	} else if len(items) > 0 {
+		if err := json.Unmarshal(items, &js.Items); err != nil {
+			return err
+		}
+	}
+	order, err := ObjectKeys(raw.Properties)
+	if err != nil {
+		return err
+	}
+	if order != nil {
+		js.PropertyOrder = order
+	}
+	return nil
+}
+
+func ObjectKeys(data []byte) ([]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil
+	}
+
+	keys := []string{}
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// ParseAdditional fills in the additionalProperties and additionalItems
+// schemas of a schema built in Go rather than unmarshaled, by decoding them
+// the way UnmarshalJSON does.
+func (js *JsonSchema) ParseAdditional() (err error) {
+	if js.AdditionalProperties == nil {
+		if js.AdditionalProperties, err = js.subschema(js.AdditionalInterface); err != nil {
+			return
+		}
+	}
+	if js.AdditionalItems == nil {
+		js.AdditionalItems, err = js.subschema(js.AdditionalItemsValue)
+	}
+	return
+}
+
+func (js *JsonSchema) subschema(in interface{}) (*JsonSchema, error) {
+	if _, ok := in.(map[string]interface{}); !ok {
+		return nil, nil
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	schema := &JsonSchema{}
+	if err = json.Unmarshal(data, schema); err != nil {
+		return nil, err
+	}
+	schema.SetRoot(js.root)
+	schema.SetBase(js.base)
+	return schema, nil
+}
+
+func (g *Generator) GoType(js *JsonSchema, collapse bool, path string) (string, error) {
+	key := memoKey{js, collapse}
+	if typ, ok := g.memo[key]; ok {
+		return typ, nil
+	}
+
+	// Types that cut a recursive cycle short depend on the call stack and can't be reused.
+	recursions := g.recursions
+	typ, err := g.goType(js, collapse, path)
+	if err == nil && recursions == g.recursions {
+		g.memo[key] = typ
+	}
+	return typ, err
+}
+
+func (g *Generator) goType(js *JsonSchema, collapse bool, path string) (string, error) {
+	entry := activeSchema{schema: js, ref: js.RefKey()}
+	if len(entry.ref.ref) == 0 {
+		entry.ref = g.DocumentKey(js)
+	}
+	if err := g.LoadRef(js); err != nil {
+		return "", err
+	}
+	// Only named types can break a cycle, so an unnamed schema that is already
+	// being generated would recurse forever.
+	entry.named = len(js.Title) > 0
+	for i := len(g.active) - 1; i >= 0 && !entry.named && !g.active[i].named; i-- {
+		if g.active[i].schema == js || (len(entry.ref.ref) > 0 && g.active[i].ref == entry.ref) {
+			if len(entry.ref.ref) == 0 {
+				entry.ref = g.active[i].ref
+			}
+			var refs []string
+			for _, active := range append(g.active[i:], entry) {
+				if len(active.ref.ref) > 0 {
+					refs = append(refs, active.ref.ref)
+				}
+			}
+			return "", CycleError(refs)
+		}
+	}
+	g.active = append(g.active, entry)
+	defer func() { g.active = g.active[:len(g.active)-1] }()
+
+	if name := g.Capitalize(js.Title); len(name) > 0 {
+		path = name
+	}
+	if len(js.CustomType) > 0 {
+		return g.ParseCustomType(js.CustomType)
+	}
+	if js.never {
+		return "struct{}", nil
+	}
+
+	if len(js.OneOf) > 0 {
+		return g.OneOfType(js, path)
+	}
+	if js.Const != nil {
+		return g.ConstType(js)
+	}
+	if len(js.AnyOf) > 0 && len(js.Properties) == 0 {
+		return g.CommonType(js.AnyOf, path)
+	}
+
+	jsType := js.Type
+	if jsType == nil {
+		jsType = js.InferredType()
+		if g.Strict && jsType != "any" {
+			if len(path) == 0 {
+				path = "schema"
+			}
+			return "", fmt.Errorf("%s: type is missing, and is not inferred as %s in strict mode", path, jsType)
+		}
+	}
+	switch t := jsType.(type) {
+	case string:
+		switch t {
+		case "any":
+			return "interface{}", nil
+		case "boolean":
+			return "bool", nil
+		case "integer":
+			if js.Format == "int32" {
+				return g.EnumType(js, "int32", js.Enum)
+			}
+			return g.EnumType(js, g.NumericType(g.IntType), js.Enum)
+		case "number":
+			if js.Format == "float" {
+				return g.EnumType(js, "float32", js.Enum)
+			}
+			return g.EnumType(js, g.NumericType(g.NumberType), js.Enum)
+		case "string":
+			switch js.Format {
+			case "date-time", "date":
+				g.imports["time"] = true
+				return "time.Time", nil
+			case "duration":
+				g.imports["time"] = true
+				return "time.Duration", nil
+			}
+			return g.EnumType(js, "string", js.Enum)
+		case "array":
+			if len(js.ItemsList) > 0 {
+				return g.TupleType(js, path)
+			}
+			if js.Items == nil {
+				return g.Unsupported(path, fmt.Errorf("Schema %+v does not have an array type.", js))
+			}
+			if err := g.LoadRef(js.Items); err != nil {
+				return "", err
+			}
+			if len(js.Items.Enum) > 0 && len(js.Items.Title) == 0 {
+				js.Items.Title = path + "Item"
+			}
+			typ, err := g.GoType(js.Items, !g.NoCollapse, path+"Item")
+			if err != nil {
+				return "", err
+			}
+			if js.UniqueItems && g.UniqueItems == "set" && g.IsOrdered(typ) {
+				return g.SetType(typ, path)
+			}
+			if js.MinItems != nil && js.MaxItems != nil && *js.MinItems == *js.MaxItems && *js.MinItems > 0 {
+				return "[" + strconv.Itoa(*js.MinItems) + "]" + typ, nil
+			}
+			return "[]" + typ, nil
+		case "object":
+			name := g.Capitalize(js.Title)
+			parents := g.EmbeddedParents(js)
+
+			if len(js.Properties) == 0 && len(parents) == 0 {
+				return g.MapType(js, path)
+			}
+
+			if len(name) == 0 && g.CollapseAll && len(path) > 0 {
+				name = path
+			}
+			if len(name) > 0 {
+				if g.inProgress[g.TypeName(name)] {
+					g.recursions++
+					g.recursive[g.TypeName(name)] = true
+					g.cuts = append(g.cuts, g.TypeName(name))
+					return "*" + g.TypeName(name), nil
+				}
+				g.inProgress[g.TypeName(name)] = true
+				defer delete(g.inProgress, g.TypeName(name))
+			}
+
+			required := make(map[string]bool)
+			for _, n := range js.Required {
+				required[n] = true
+			}
+
+			fields := make(map[string]bool)
+			protoFields := make(map[int]string)
+			var checks, nested, sentinels, limits, defaults, omitFields, omitKeys, keys []string
+			var accessors []structField
+			var models []FieldModel
+			src := "struct {\n"
+			for _, parent := range parents {
+				typ, err := g.GoType(parent, true, g.Capitalize(parent.Title))
+				if err != nil {
+					return "", err
+				}
+				fields[typ] = true
+				if g.YAMLTags {
+					src += typ + " `yaml:\",inline\"`\n"
+					models = append(models, FieldModel{Type: typ, Tag: `yaml:",inline"`})
+				} else {
+					src += typ + "\n"
+					models = append(models, FieldModel{Type: typ})
+				}
+				parentChecks, err := g.ValidationChecks("x."+typ, typ, &JsonSchema{})
+				if err != nil {
+					return "", err
+				}
+				checks = append(checks, parentChecks...)
+				nested = append(nested, parentChecks...)
+			}
+			// Names set with x-go-name are reserved first, so derived names
+			// never take them.
+			for _, n := range g.PropertyNames(js) {
+				if name := js.Properties[n].GoName; len(name) > 0 {
+					if !token.IsIdentifier(name) || !token.IsExported(name) {
+						return "", fmt.Errorf("Invalid x-go-name %q: expected an exported Go identifier", name)
+					} else if fields[name] {
+						return "", fmt.Errorf("Duplicate field name from x-go-name: %s", name)
+					}
+					fields[name] = true
+				}
+			}
+			jsonKeys, err := g.JSONKeys(js, path)
+			if err != nil {
+				return "", err
+			}
+			requestSrc, responseSrc := src, src
+			requestModels, responseModels := models, models
+			for _, n := range g.PropertyNames(js) {
+				if prop := js.Properties[n]; (len(prop.Enum) > 0 || len(prop.OneOf) > 0 || prop.Const != nil) && len(prop.Title) == 0 {
+					prop.Title = path + g.Capitalize(n)
+				}
+				alts := js.alternatives[n]
+				if len(alts) == 0 {
+					alts = []*JsonSchema{js.Properties[n]}
+				}
+				cuts := len(g.cuts)
+				typ, err := g.CommonType(alts, path+g.Capitalize(n))
+				if err != nil {
+					return "", err
+				}
+				// A field that leads back to a type being generated is cut
+				// short with a pointer wherever the cycle is entered, so it
+				// is one in every expansion of the cycle.
+				if _, ok := g.types[typ]; ok && g.LoopsBack(cuts) && !g.IsNilable(typ) {
+					typ = "*" + typ
+				}
+				// x-go-type is used verbatim, since it may name an interface.
+				if _, ok := g.OptionalElem(typ); !ok && len(js.Properties[n].CustomType) == 0 && (js.Properties[n].Nullable || js.conditional[n] || g.Pointers && !required[n]) && !g.IsNilable(typ) {
+					typ = g.NullableType(typ)
+				}
+				tag := jsonKeys[n]
+				keys = append(keys, tag)
+				if g.OmitEmpty && !required[n] {
+					tag += ",omitempty"
+				}
+				tags := []string{"json:" + strconv.Quote(tag)}
+				if g.YAMLTags {
+					tags = append(tags, "yaml:"+strconv.Quote(tag))
+				}
+				if number := js.Properties[n].ProtoField; number != nil {
+					if other, ok := protoFields[*number]; ok {
+						return "", fmt.Errorf("Duplicate x-proto-field %d: used by %s and %s", *number, other, n)
+					}
+					protoFields[*number] = n
+					protoTags, err := g.ProtoTags(n, *number, typ)
+					if err != nil {
+						return "", err
+					}
+					tags = append(tags, protoTags...)
+				}
+				if extra := strings.TrimSpace(js.Properties[n].GoTags); len(extra) > 0 {
+					tags = append(tags, extra)
+				}
+				field := js.Properties[n].GoName
+				if len(field) == 0 {
+					field = UniqueName(g.Capitalize(n), fields)
+				}
+				g.fields[js] = append(g.fields[js], structField{n, field, typ})
+				comment := g.Comment(field, js.Properties[n].Doc())
+				line := comment + field + " " + typ + " " + StructTag(tags) + "\n"
+				model := FieldModel{Name: field, Type: typ, Tag: strings.Join(tags, " "), Doc: CommentText(comment)}
+				src += line
+				models = append(models, model)
+				if !js.Properties[n].ReadOnly {
+					requestSrc += line
+					requestModels = append(requestModels[:len(requestModels):len(requestModels)], model)
+				}
+				if !js.Properties[n].WriteOnly {
+					responseSrc += line
+					responseModels = append(responseModels[:len(responseModels):len(responseModels)], model)
+				}
+				fieldChecks, err := g.ValidationChecks("x."+field, typ, js.Properties[n])
+				if err != nil {
+					return "", err
+				}
+				nested = append(nested, NestSentinels(field, n, fieldChecks)...)
+				if len(name) > 0 {
+					sentinels = append(sentinels, g.Sentinels(g.TypeName(name), field, n, fieldChecks)...)
+				}
+				checks = append(checks, fieldChecks...)
+				if elem, ok := g.OptionalElem(typ); ok {
+					if def, ok := g.DefaultLiteral(elem, js.Properties[n].Default); ok {
+						defaults = append(defaults, "x."+field+" = "+g.OptionalLiteral(typ, def))
+					}
+				} else if def, ok := g.DefaultLiteral(typ, js.Properties[n].Default); ok {
+					if strings.HasPrefix(typ, "*") {
+						defaults = append(defaults, "x."+field+" = new("+typ[1:]+")", "*x."+field+" = "+def)
+					} else {
+						defaults = append(defaults, "x."+field+" = "+def)
+					}
+				}
+				if g.Limits && len(name) > 0 {
+					limits = append(limits, g.LimitConsts(name+field, typ, js.Properties[n])...)
+				}
+				if g.DeepOmitEmpty && !required[n] && g.IsStruct(typ) {
+					omitFields = append(omitFields, field)
+					omitKeys = append(omitKeys, jsonKeys[n])
+				}
+				if g.Accessors && strings.HasPrefix(typ, "*") {
+					accessors = append(accessors, structField{n, field, typ})
+				}
+			}
+			splitRW := g.SplitRW && (requestSrc != src || responseSrc != src)
+			var catchAll structField
+			if g.CatchAll && len(name) > 0 && collapse {
+				mapType, err := g.MapType(js, path)
+				if err != nil {
+					return "", err
+				}
+				if strings.HasPrefix(mapType, "map[") {
+					if len(g.CatchAllType) > 0 {
+						mapType = g.CatchAllType
+					}
+					catchAll = structField{"", UniqueName(g.CatchAllField, fields), mapType}
+					tags := []string{`json:"-"`}
+					if g.YAMLTags && mapType == "json.RawMessage" {
+						tags = append(tags, `yaml:"-"`)
+					} else if g.YAMLTags {
+						tags = append(tags, `yaml:",inline"`)
+					}
+					src += catchAll.name + " " + mapType + " " + StructTag(tags) + "\n"
+					models = append(models, FieldModel{Name: catchAll.name, Type: mapType, Tag: strings.Join(tags, " ")})
+					// The keys of raw JSON can't be checked without decoding it.
+					if mapType != "json.RawMessage" {
+						extraChecks, err := g.ValidationChecks("x."+catchAll.name, mapType, &JsonSchema{PropertyNames: js.PropertyNames})
+						if err != nil {
+							return "", err
+						}
+						sentinels = append(sentinels, g.Sentinels(g.TypeName(name), catchAll.name, "additionalProperties", extraChecks)...)
+						checks = append(checks, extraChecks...)
+					}
+				}
+			}
+			src += "}"
+			requestSrc += "}"
+			responseSrc += "}"
+			g.structs[src] = models
+			g.structs[requestSrc] = requestModels
+			g.structs[responseSrc] = responseModels
+
+			if len(name) > 0 && !collapse {
+				// A type that refers to itself can only be inlined where
+				// it starts, so it is declared after all.
+				if g.recursive[g.TypeName(name)] {
+					delete(g.inProgress, g.TypeName(name))
+					return g.GoType(js, true, path)
+				}
+				name = ""
+			}
+			if len(name) > 0 {
+				// Copies of a file read for different refs aren't the same
+				// schema, but generate the same source.
+				renamed, err := g.ClaimName(name, path, func(name string) bool {
+					return g.schemas[g.TypeName(name)] == js.Origin() || g.types[g.TypeName(name)] == src
+				})
+				if err != nil {
+					return "", err
+				}
+				if renamed != name {
+					delete(g.fields, js)
+					delete(g.inProgress, g.TypeName(name))
+					js.Title = renamed
+					return g.GoType(js, collapse, path)
+				}
+				g.types[g.TypeName(name)] = src
+				g.docs[g.TypeName(name)] = js.Doc()
+				g.schemas[g.TypeName(name)] = js.Origin()
+				if splitRW {
+					g.types[g.TypeName(name+"Request")] = requestSrc
+					g.docs[g.TypeName(name+"Request")] = "is " + g.TypeName(name) + " without its readOnly properties, for request bodies."
+					g.types[g.TypeName(name+"Response")] = responseSrc
+					g.docs[g.TypeName(name+"Response")] = "is " + g.TypeName(name) + " without its writeOnly properties, for response bodies."
+				}
+				if len(limits) > 0 {
+					g.consts[g.TypeName(name)] = "const (\n" + strings.Join(limits, "\n") + "\n)"
+				}
+				methods := ""
+				if len(defaults) > 0 {
+					methods += "func New" + g.TypeName(name) + "() " + g.TypeName(name) + " {\n" +
+						"var x " + g.TypeName(name) + "\n" + strings.Join(defaults, "\n") + "\nreturn x\n}\n\n"
+				}
+				for _, field := range accessors {
+					methods += g.AccessorMethods(g.TypeName(name), field.name, field.typ[1:])
+				}
+				if example, ok := js.FirstExample().(map[string]interface{}); ok {
+					if lit, ok := g.ExampleLiteral(js, g.TypeName(name), example); ok {
+						methods += "var Example" + g.TypeName(name) + " = " + lit + "\n\n"
+					}
+				}
+				if g.GenTests {
+					g.examples[g.TypeName(name)], g.mismatched[g.TypeName(name)] = g.RoundTripExamples(js)
+				}
+				marshal := "MarshalJSON"
+				if len(catchAll.name) > 0 {
+					marshal = "marshalFields"
+				}
+				if len(omitFields) > 0 {
+					methods += g.OmitEmptyMethod(g.TypeName(name), marshal, omitFields, omitKeys)
+				}
+				unmarshal := "UnmarshalJSON"
+				if g.LooseKeys && len(keys) > 0 {
+					unmarshal = "unmarshalFields"
+				}
+				if len(catchAll.name) > 0 {
+					methods += g.CatchAllMethods(g.TypeName(name), unmarshal, catchAll.name, catchAll.typ, keys, len(omitFields) > 0)
+				}
+				strictUnmarshal := g.StrictUnmarshal && js.IsClosed() && len(js.PatternProperties) == 0
+				if strictUnmarshal {
+					methods += g.StrictUnmarshalMethod(g.TypeName(name), unmarshal)
+				}
+				if unmarshal != "UnmarshalJSON" {
+					methods += g.LooseKeysMethod(g.TypeName(name), keys, len(catchAll.name) > 0 || strictUnmarshal)
+				}
+				if len(sentinels) > 0 {
+					methods += "var (\n" + strings.Join(sentinels, "\n") + "\n)\n\n"
+				}
+				// A schema reached through several refs is generated again for
+				// each of them, so its methods replace the ones it added before
+				// rather than repeating them.
+				g.methods[g.TypeName(name)] = methods + strings.TrimPrefix(g.methods[g.TypeName(name)], g.ownMethods[g.TypeName(name)])
+				g.ownMethods[g.TypeName(name)] = methods
+				if len(checks) > 0 {
+					g.validations[g.TypeName(name)] = checks
+				}
+				return g.TypeName(name), nil
+			}
+			// An untitled struct has no Validate of its own, so its checks
+			// are made by the types it is a field of.
+			if len(nested) > 0 {
+				g.inline[js.Origin()] = nested
+			}
+			if g.Dedup {
+				return g.AnonType(js, src, path), nil
+			}
+			return src, nil
+		default:
+			return g.Unsupported(path, errors.New("Unknown type string: "+t))
+		}
+	case []interface{}:
+		var types []interface{}
+		for _, v := range t {
+			if v != "null" {
+				types = append(types, v)
+			}
+		}
+		if len(types) != 1 {
+			return g.Fallback(path, fmt.Sprintf("type has %d non-null types", len(types))), nil
+		}
+		single := *js
+		single.Type = types[0]
+		single.origin = js.Origin()
+		typ, err := g.GoType(&single, collapse, path)
+		if err != nil || len(types) == len(t) || g.IsNilable(typ) {
+			return typ, err
+		}
+		return g.NullableType(typ), nil
+	default:
+		return g.Unsupported(path, fmt.Errorf("Unknown type: %+v", js.Type))
+	}
+}
+
+func (g *Generator) Unsupported(path string, err error) (string, error) {
+	if g.Strict {
+		return "", err
+	}
+	if len(path) == 0 {
+		path = "schema"
+	}
+	g.Warnings = append(g.Warnings, fmt.Sprintf("%s: %s, using interface{}", path, strings.TrimSuffix(err.Error(), ".")))
+	return "interface{}", nil
+}
+
+// Warn adds a warning, unless an earlier expansion of the same schema added
+// it already.
+func (g *Generator) Warn(warning string) {
+	for _, w := range g.Warnings {
+		if w == warning {
+			return
+		}
+	}
+	g.Warnings = append(g.Warnings, warning)
+}
+
+// Fallback logs why the schema at path becomes interface{} and returns it.
+func (g *Generator) Fallback(path, reason string) string {
+	if len(path) == 0 {
+		path = "schema"
+	}
+	g.Logf("%s: %s, using interface{}", path, reason)
+	return "interface{}"
+}
+
+// Logf writes a debug message to Log, if it is set.
+func (g *Generator) Logf(format string, args ...interface{}) {
+	if g.Log != nil {
+		fmt.Fprintf(g.Log, format+"\n", args...)
+	}
+}
+
+var anonPattern = regexp.MustCompile("\x00[0-9]+\x00")
+
+func (g *Generator) AnonType(js *JsonSchema, src, path string) string {
+	anon, ok := g.anon[src]
+	if !ok {
+		anon = &AnonType{ID: len(g.anon), Name: path, Uses: make(map[*JsonSchema]bool)}
+		g.anon[src] = anon
+	}
+	anon.Uses[js] = true
+	return "\x00" + strconv.Itoa(anon.ID) + "\x00"
+}
+
+func (g *Generator) HoistAnonTypes() {
+	srcs := make([]string, len(g.anon))
+	for src, anon := range g.anon {
+		srcs[anon.ID] = src
+	}
+
+	used := make(map[string]bool)
+	for name := range g.types {
+		used[name] = true
+	}
+
+	names := make([]string, len(srcs))
+	for id, src := range srcs {
+		if anon := g.anon[src]; len(anon.Uses) > 1 {
+			name := anon.Name
+			if len(name) == 0 {
+				name = "Anon"
+			}
+			names[id] = UniqueName(g.TypeName(name), used)
+		}
+	}
+
+	var expand func(string) string
+	expand = func(src string) string {
+		return anonPattern.ReplaceAllStringFunc(src, func(match string) string {
+			id, _ := strconv.Atoi(match[1 : len(match)-1])
+			if len(names[id]) > 0 {
+				return names[id]
+			}
+			return expand(srcs[id])
+		})
+	}
+
+	for id, name := range names {
+		if len(name) > 0 {
+			g.Logf("Hoisted shared anonymous struct into type %s", name)
+			g.types[name] = expand(srcs[id])
+		}
+	}
+	for name, src := range g.types {
+		g.types[name] = expand(src)
+	}
+	for name, src := range g.methods {
+		g.methods[name] = expand(src)
+	}
+	structs := make(map[string][]FieldModel, len(g.structs))
+	for src, models := range g.structs {
+		expanded := make([]FieldModel, len(models))
+		for i, model := range models {
+			model.Type = expand(model.Type)
+			expanded[i] = model
+		}
+		structs[expand(src)] = expanded
+	}
+	g.structs = structs
+}
+
+func (g *Generator) MapType(js *JsonSchema, path string) (string, error) {
+	values := make([]*JsonSchema, 0, len(js.PatternProperties)+1)
+	for _, pattern := range SortedKeys(js.PatternProperties) {
+		values = append(values, js.PatternProperties[pattern])
+	}
+	if js.AdditionalProperties != nil {
+		values = append(values, js.AdditionalProperties)
+	} else if allowed, ok := js.AdditionalInterface.(bool); ok {
+		if !allowed && len(values) == 0 {
+			return "struct{}", nil
+		} else if allowed {
+			values = append(values, &JsonSchema{Type: "any"})
+		}
+	}
+	if len(values) == 0 {
+		return g.Fallback(path, "object without properties or additionalProperties"), nil
+	}
+
+	keyType := "string"
+	if g.MapKeys && js.PropertyNames != nil {
+		keys := *js.PropertyNames
+		if err := g.LoadRef(&keys); err != nil {
+			return "", err
+		}
+		if len(keys.Title) == 0 {
+			keys.Title = path + "Key"
+		}
+		if keys.Type == nil {
+			keys.Type = "string"
+		}
+		if len(keys.Enum) > 0 {
+			typ, err := g.GoType(&keys, true, path+"Key")
+			if err != nil {
+				return "", err
+			}
+			if g.types[typ] == "string" {
+				keyType = typ
+			}
+		}
+	}
+
+	var valueType string
+	for i, value := range values {
+		typ, err := g.GoType(value, !g.NoCollapse, path+"Value")
+		if err != nil {
+			return "", err
+		}
+		if i > 0 && typ != valueType {
+			g.Logf("%s: map values have different types, using interface{}", path)
+			return "map[" + keyType + "]interface{}", nil
+		}
+		valueType = typ
+	}
+	return "map[" + keyType + "]" + valueType, nil
+}
+
+// PropertyNames returns the property keys in field order, leaving out
+// properties with a false schema since they can never be present.
+func (g *Generator) PropertyNames(js *JsonSchema) []string {
+	var names []string
+	for _, n := range SortedKeys(js.Properties) {
+		if !js.Properties[n].never {
+			names = append(names, n)
+		}
+	}
+	if !g.PreserveOrder || len(js.PropertyOrder) == 0 {
+		return names
+	}
+
+	ordered := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, n := range js.PropertyOrder {
+		if prop, ok := js.Properties[n]; ok && !prop.never && !seen[n] {
+			ordered = append(ordered, n)
+			seen[n] = true
+		}
+	}
+	for _, n := range names {
+		if !seen[n] {
+			ordered = append(ordered, n)
+		}
+	}
+	return ordered
+}
+
+var intTypes = map[string]bool{"int": true, "int32": true, "int64": true, "json.Number": true}
+var numberTypes = map[string]bool{"float32": true, "float64": true, "json.Number": true}
+var catchAllTypes = map[string]bool{"json.RawMessage": true, "map[string]interface{}": true}
+var uniqueItemsModes = map[string]bool{"": true, "set": true, "validate": true}
+
+func (g *Generator) NumericType(typ string) string {
+	if typ == "json.Number" {
+		g.imports["encoding/json"] = true
+	}
+	return typ
+}
+
+func (g *Generator) ConstType(js *JsonSchema) (string, error) {
+	base := "interface{}"
+	switch v := js.Const.(type) {
+	case string:
+		base = "string"
+	case bool:
+		base = "bool"
+	case float64:
+		base = g.NumericType(g.NumberType)
+		if t, _ := js.Type.(string); t == "integer" || (t != "number" && v == math.Trunc(v)) {
+			base = g.NumericType(g.IntType)
+		}
+	}
+	if base == "interface{}" {
+		return base, nil
+	}
+	return g.EnumType(js, base, []interface{}{js.Const})
+}
+
+func (g *Generator) EnumType(js *JsonSchema, base string, values []interface{}) (string, error) {
+	name := g.Capitalize(js.Title)
+	if len(values) == 0 || len(name) == 0 {
+		return base, nil
+	}
+
+	name, err := g.ClaimName(name, name, func(name string) bool {
+		src, _ := g.EnumConsts(name, base, values)
+		return g.types[g.TypeName(name)] == base && g.consts[g.TypeName(name)] == src
+	})
+	if err != nil {
+		return "", err
+	}
+	typeName := g.TypeName(name)
+	src, names := g.EnumConsts(name, base, values)
+	g.types[typeName] = base
+	g.docs[typeName] = js.Doc()
+	g.consts[typeName] = src
+	if g.Stringer {
+		g.imports["fmt"] = true
+		namesVar := strings.ToLower(typeName[:1]) + typeName[1:] + "Names"
+		g.methods[typeName] = "var " + namesVar + " = map[" + typeName + "]string{\n" + names + "}\n\n" +
+			"func (v " + typeName + ") String() string {\n" +
+			"if name, ok := " + namesVar + "[v]; ok {\nreturn name\n}\n" +
+			"return fmt.Sprint(" + base + "(v))\n}\n\n"
+	}
+	return typeName, nil
+}
+
+// EnumConsts returns the const block of an enum type named name, and the
+// entries of the map from its constants to their names.
+func (g *Generator) EnumConsts(name, base string, values []interface{}) (src, names string) {
+	typeName := g.TypeName(name)
+	src = "const (\n"
+	// Constants keep the order of the enum. Repeated values are dropped, and
+	// values whose names collide, like "a-b" and "a.b", get numbered.
+	used, seen := make(map[string]bool), make(map[string]bool)
+	for _, v := range values {
+		var value string
+		switch v := v.(type) {
+		case string:
+			if base != "string" {
+				continue
+			}
+			value = strconv.Quote(v)
+		case bool:
+			if base != "bool" {
+				continue
+			}
+			value = strconv.FormatBool(v)
+		case float64:
+			if base == "string" || base == "bool" || (strings.HasPrefix(base, "int") && v != math.Trunc(v)) {
+				continue
+			}
+			value = strconv.FormatFloat(v, 'g', -1, 64)
+			if base == "json.Number" {
+				value = strconv.Quote(value)
+			}
+		default:
+			continue
+		}
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		constName := UniqueName(name+ConstName(v), used)
+		src += constName + " " + typeName + " = " + value + "\n"
+		if str, ok := v.(string); ok {
+			names += constName + ": " + strconv.Quote(str) + ",\n"
+		} else {
+			names += constName + ": " + strconv.Quote(constName) + ",\n"
+		}
+	}
+	src += ")"
+	return src, names
+}
+
+// ParentTitle returns the capitalized title of the nearest schema being
+// generated that encloses the current one and has a title.
+func (g *Generator) ParentTitle() string {
+	for i := len(g.active) - 2; i >= 0; i-- {
+		if name := g.Capitalize(g.active[i].schema.Title); len(name) > 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+// ClaimName returns the name a type generated at path is registered under.
+// That is name, unless a different type has it already: same reports
+// whether the type registered under a name is the one being generated.
+func (g *Generator) ClaimName(name, path string, same func(name string) bool) (string, error) {
+	if _, ok := g.types[g.TypeName(name)]; !ok || same(name) {
+		return name, nil
+	}
+	// Another schema with the same title got the name first.
+	if parent := g.ParentTitle(); g.NamespaceTitles && len(parent) > 0 {
+		if _, ok := g.types[g.TypeName(parent+name)]; !ok || same(parent+name) {
+			g.Logf("Renamed type %s to %s, since another schema has the same title", g.TypeName(name), g.TypeName(parent+name))
+			return parent + name, nil
+		}
+	}
+	// Top-level schemas and definitions keep their names.
+	if g.Strict || len(g.active) <= 1 {
+		return "", fmt.Errorf("Type %s is generated from two different schemas; give one of them another title, or pass -namespace-titles to prefix it with its parent's", g.TypeName(name))
+	}
+	renamed := ""
+	for i := 2; len(renamed) == 0; i++ {
+		if _, ok := g.types[g.TypeName(name+strconv.Itoa(i))]; !ok || same(name+strconv.Itoa(i)) {
+			renamed = name + strconv.Itoa(i)
+		}
+	}
+	if _, ok := g.types[g.TypeName(renamed)]; !ok {
+		g.Warnings = append(g.Warnings, fmt.Sprintf("%s: type %s is already generated from another schema, using %s", path, g.TypeName(name), g.TypeName(renamed)))
+	}
+	return renamed, nil
+}
+
+// LoopsBack reports whether a cycle was cut short since the first cuts, at a
+// type that is still being generated.
+func (g *Generator) LoopsBack(cuts int) bool {
+	for _, name := range g.cuts[cuts:] {
+		if g.inProgress[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generator) TypeName(name string) string {
+	return g.StructPrefix + name + g.StructSuffix
+}
+
+func (g *Generator) OneOfType(js *JsonSchema, path string) (string, error) {
+	name := g.Capitalize(js.Title)
+	if len(name) == 0 {
+		return g.Fallback(path, "oneOf without a title"), nil
+	}
+
+	name, err := g.ClaimName(name, path, func(name string) bool {
+		return SameOneOf(g.schemas[g.TypeName(name)], js, name)
+	})
+	if err != nil {
+		return "", err
+	}
+	typeName := g.TypeName(name)
+	marker := "is" + typeName
+	g.types[typeName] = "interface {\n" + marker + "()\n}"
+	g.docs[typeName] = js.Doc()
+	g.schemas[typeName] = js
+
+	var variants []*JsonSchema
+	var variantNames []string
+	for i, variant := range js.OneOf {
+		if len(variant.Title) == 0 {
+			variant.Title = name + strconv.Itoa(i+1)
+		}
+		typ, err := g.GoType(variant, true, g.Capitalize(variant.Title))
+		if err != nil {
+			return "", err
+		}
+		if typ == "interface{}" {
+			continue
+		}
+
+		variantName := typ
+		if _, ok := g.types[typ]; !ok {
+			claimed, err := g.ClaimName(g.Capitalize(variant.Title), path, func(name string) bool {
+				return g.types[g.TypeName(name)] == typ
+			})
+			if err != nil {
+				return "", err
+			}
+			variantName = g.TypeName(claimed)
+			g.types[variantName] = typ
+			g.docs[variantName] = variant.Doc()
+		}
+		g.methods[variantName] += "func (" + variantName + ") " + marker + "() {}\n\n"
+		variants = append(variants, variant)
+		variantNames = append(variantNames, variantName)
+	}
+	g.variants[typeName] = variantNames
+	if len(variants) == 0 {
+		return typeName, nil
+	}
+
+	wrapperSrc := "struct {\n" + typeName + "\n}"
+	g.structs[wrapperSrc] = []FieldModel{{Type: typeName}}
+	wrapper, err := g.ClaimName(name+"Value", path, func(name string) bool {
+		return g.types[g.TypeName(name)] == wrapperSrc
+	})
+	if err != nil {
+		return "", err
+	}
+	wrapper = g.TypeName(wrapper)
+	g.imports["encoding/json"] = true
+	g.imports["fmt"] = true
+	g.types[wrapper] = wrapperSrc
+	g.docs[wrapper] = "holds a " + typeName + " and encodes it as the JSON of the held variant."
+
+	methods := "func (v " + wrapper + ") MarshalJSON() ([]byte, error) {\n"
+	methods += "return json.Marshal(v." + typeName + ")\n}\n\n"
+	methods += "func (v *" + wrapper + ") UnmarshalJSON(data []byte) error {\n"
+	methods += "if string(data) == \"null\" {\nv." + typeName + " = nil\nreturn nil\n}\n"
+	if key, values := Discriminator(variants); len(key) > 0 {
+		key = g.JSONKey(key)
+		methods += "var probe struct {\nValue interface{} " + StructTag([]string{"json:" + strconv.Quote(key)}) + "\n}\n"
+		methods += "if err := json.Unmarshal(data, &probe); err != nil {\nreturn err\n}\n"
+		methods += "switch probe.Value {\n"
+		for i, variantName := range variantNames {
+			methods += "case " + values[i] + ":\n"
+			methods += "var x " + variantName + "\n"
+			methods += "if err := json.Unmarshal(data, &x); err != nil {\nreturn err\n}\n"
+			methods += "v." + typeName + " = x\n"
+		}
+		methods += "default:\n"
+		methods += "return fmt.Errorf(" + strconv.Quote("unknown "+typeName+" "+key+": %v") + ", probe.Value)\n}\n"
+		methods += "return nil\n}\n\n"
+	} else {
+		g.imports["bytes"] = true
+		methods += "decode := func(x interface{}) error {\n"
+		methods += "dec := json.NewDecoder(bytes.NewReader(data))\ndec.DisallowUnknownFields()\nreturn dec.Decode(x)\n}\n"
+		methods += "var err error\n"
+		for i, variantName := range variantNames {
+			x := "x" + strconv.Itoa(i+1)
+			methods += "var " + x + " " + variantName + "\n"
+			methods += "if err = decode(&" + x + "); err == nil {\nv." + typeName + " = " + x + "\nreturn nil\n}\n"
+		}
+		methods += "return fmt.Errorf(" + strconv.Quote("no "+typeName+" variant matches: %v") + ", err)\n}\n\n"
+	}
+	g.methods[wrapper] = methods
+	return wrapper, nil
+}
+
+// SameOneOf reports whether other, registered under name, is the oneOf
+// schema js. Copies of a file read for different refs are matched by the
+// titles of their variants.
+func SameOneOf(other, js *JsonSchema, name string) bool {
+	if other == nil || other.Origin() == js.Origin() {
+		return other != nil
+	}
+	if len(other.OneOf) != len(js.OneOf) {
+		return false
+	}
+	for i, variant := range js.OneOf {
+		title := variant.Title
+		if len(title) == 0 {
+			title = name + strconv.Itoa(i+1)
+		}
+		if other.OneOf[i].Title != title {
+			return false
+		}
+	}
+	return true
+}
+
+func Discriminator(variants []*JsonSchema) (string, []string) {
+	for _, key := range SortedKeys(variants[0].Properties) {
+		values := make([]string, len(variants))
+		seen := make(map[string]bool)
+		for i, variant := range variants {
+			prop, ok := variant.Properties[key]
+			if !ok {
+				break
+			}
+			switch v := prop.Const.(type) {
+			case string:
+				values[i] = strconv.Quote(v)
+			case bool:
+				values[i] = strconv.FormatBool(v)
+			case float64:
+				values[i] = "float64(" + NumberLiteral(v) + ")"
+			}
+			if len(values[i]) == 0 || seen[values[i]] {
+				values[i] = ""
+				break
+			}
+			seen[values[i]] = true
+		}
+		if len(values[len(values)-1]) > 0 {
+			return key, values
+		}
+	}
+	return "", nil
+}
+
+func (g *Generator) ParseCustomType(spec string) (string, error) {
+	typ := strings.TrimLeft(spec, "*[]")
+	prefix := spec[:len(spec)-len(typ)]
+
+	dot := strings.LastIndex(typ, ".")
+	if dot < strings.LastIndex(typ, "/") {
+		return "", fmt.Errorf("Invalid x-go-type %q: expected import/path.Type", spec)
+	}
+	if dot < 0 {
+		return spec, nil
+	}
+
+	imp, ident := typ[:dot], typ[dot+1:]
+	if len(imp) == 0 || !token.IsIdentifier(ident) {
+		return "", fmt.Errorf("Invalid x-go-type %q: expected import/path.Type", spec)
+	}
+	g.imports[imp] = true
+	return prefix + ImportName(imp) + "." + ident, nil
+}
+
+func (g *Generator) DefaultLiteral(typ string, value interface{}) (string, bool) {
+	base := g.BaseType(strings.TrimPrefix(typ, "*"))
+	switch v := value.(type) {
+	case string:
+		if base == "string" {
+			return strconv.Quote(v), true
+		}
+	case bool:
+		if base == "bool" || g.types[strings.TrimPrefix(typ, "*")] == "bool" {
+			return strconv.FormatBool(v), true
+		}
+	case float64:
+		if base == "float32" || base == "float64" || (strings.HasPrefix(base, "int") && v == math.Trunc(v)) {
+			return NumberLiteral(v), true
+		}
+		if base == "json.Number" {
+			return strconv.Quote(NumberLiteral(v)), true
+		}
+	}
+	return "", false
+}
+
+func (js *JsonSchema) FirstExample() interface{} {
+	if len(js.Examples) > 0 {
+		return js.Examples[0]
+	}
+	return js.Example
+}
+
+func (g *Generator) ExampleLiteral(js *JsonSchema, typ string, value interface{}) (string, bool) {
+	if elem, ok := g.OptionalElem(typ); ok {
+		lit, ok := g.ExampleLiteral(js, elem, value)
+		return g.OptionalLiteral(typ, lit), ok
+	}
+	if strings.HasPrefix(typ, "[]") {
+		list, ok := value.([]interface{})
+		if !ok || js.Items == nil {
+			return "", false
+		}
+		elems := make([]string, len(list))
+		for i, v := range list {
+			if elems[i], ok = g.ExampleLiteral(js.Items, typ[2:], v); !ok {
+				return "", false
+			}
+		}
+		return typ + "{" + strings.Join(elems, ", ") + "}", true
+	}
+
+	if obj, ok := value.(map[string]interface{}); ok {
+		name := strings.TrimPrefix(typ, "*")
+		fields, ok := g.fields[js]
+		if !ok || !strings.HasPrefix(g.types[name], "struct") {
+			return "", false
+		}
+		src, matched := name+"{\n", 0
+		for _, field := range fields {
+			if v, ok := obj[field.key]; ok {
+				if lit, ok := g.ExampleLiteral(js.Properties[field.key], field.typ, v); ok {
+					src += field.name + ": " + lit + ",\n"
+					matched++
+				}
+			}
+		}
+		if matched == 0 {
+			return "", false
+		}
+		src += "}"
+		if name != typ {
+			src = "&" + src
+		}
+		return src, true
+	}
+
+	if elem := strings.TrimPrefix(typ, "*"); elem != typ {
+		// Literals can't be addressed, so pointers take a copy.
+		lit, ok := g.ExampleLiteral(js, elem, value)
+		if !ok {
+			return "", false
+		}
+		return "func() " + typ + " { v := " + elem + "(" + lit + "); return &v }()", true
+	}
+	return g.DefaultLiteral(typ, value)
+}
+
+func (g *Generator) AccessorMethods(name, field, typ string) string {
+	src := "func (x *" + name + ") Get" + field + "() " + typ + " {\n"
+	src += "if x == nil || x." + field + " == nil {\nvar zero " + typ + "\nreturn zero\n}\n"
+	src += "return *x." + field + "\n}\n\n"
+	src += "func (x *" + name + ") Set" + field + "(v " + typ + ") {\n"
+	src += "x." + field + " = &v\n}\n\n"
+	return src
+}
+
+func (js *JsonSchema) IsClosed() bool {
+	allowed, ok := js.AdditionalInterface.(bool)
+	return ok && !allowed
+}
+
+func (g *Generator) StrictUnmarshalMethod(name, method string) string {
+	g.imports["bytes"] = true
+	g.imports["encoding/json"] = true
+	return "func (x *" + name + ") " + method + "(data []byte) error {\n" +
+		"type plain " + name + "\n" +
+		"dec := json.NewDecoder(bytes.NewReader(data))\n" +
+		"dec.DisallowUnknownFields()\n" +
+		"return dec.Decode((*plain)(x))\n}\n\n"
+}
+
+// NormalizeKey lowercases key and drops everything but letters and digits, so
+// "Created_At" and "createdAt" are both "createdat".
+func NormalizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, key)
+}
+
+// LooseKeysMethod returns an UnmarshalJSON method that renames incoming keys
+// to the keys of the struct they match after NormalizeKey, unless the exact
+// key is also present. Keys that normalize the same as another key of the
+// struct only match exactly. With fields, the renamed object is decoded by
+// unmarshalFields instead of directly.
+func (g *Generator) LooseKeysMethod(name string, keys []string, fields bool) string {
+	g.imports["encoding/json"] = true
+	g.imports["strings"] = true
+	g.imports["unicode"] = true
+
+	table := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	for _, key := range keys {
+		if normalized := NormalizeKey(key); ambiguous[normalized] {
+			continue
+		} else if _, ok := table[normalized]; ok {
+			delete(table, normalized)
+			ambiguous[normalized] = true
+		} else {
+			table[normalized] = key
+		}
+	}
+	tableVar := strings.ToLower(name[:1]) + name[1:] + "Keys"
+	src := "var " + tableVar + " = map[string]string{\n"
+	for _, normalized := range SortedKeys(table) {
+		src += strconv.Quote(normalized) + ": " + strconv.Quote(table[normalized]) + ",\n"
+	}
+	src += "}\n\n"
+
+	src += "func (x *" + name + ") UnmarshalJSON(data []byte) error {\n"
+	src += "var object map[string]json.RawMessage\n"
+	src += "if err := json.Unmarshal(data, &object); err != nil {\nreturn err\n}\n"
+	src += "normalize := func(r rune) rune {\n"
+	src += "if unicode.IsLetter(r) || unicode.IsDigit(r) {\nreturn unicode.ToLower(r)\n}\n"
+	src += "return -1\n}\n"
+	src += "for key, value := range object {\n"
+	src += "known, ok := " + tableVar + "[strings.Map(normalize, key)]\n"
+	src += "if !ok || known == key {\ncontinue\n}\n"
+	src += "if _, exact := object[known]; !exact {\nobject[known] = value\n}\n"
+	src += "delete(object, key)\n}\n"
+	src += "data, err := json.Marshal(object)\n"
+	src += "if err != nil {\nreturn err\n}\n"
+	if fields {
+		return src + "return x.unmarshalFields(data)\n}\n\n"
+	}
+	src += "type plain " + name + "\n"
+	return src + "return json.Unmarshal(data, (*plain)(x))\n}\n\n"
+}
+
+func (g *Generator) IsStruct(typ string) bool {
+	return strings.HasPrefix(typ, "struct") || strings.HasPrefix(g.types[typ], "struct") || typ == "time.Time"
+}
+
+func (g *Generator) OmitEmptyMethod(name, method string, fields, keys []string) string {
+	g.imports["encoding/json"] = true
+	g.imports["reflect"] = true
+
+	src := "func (x " + name + ") " + method + "() ([]byte, error) {\n"
+	src += "type plain " + name + "\n"
+	src += "out := struct {\nplain\n"
+	for i, field := range fields {
+		src += field + " json.RawMessage " + StructTag([]string{"json:" + strconv.Quote(keys[i]+",omitempty")}) + "\n"
+	}
+	src += "}{plain: plain(x)}\n"
+	src += "var err error\n"
+	for _, field := range fields {
+		src += "if !reflect.ValueOf(x." + field + ").IsZero() {\n"
+		src += "if out." + field + ", err = json.Marshal(x." + field + "); err != nil {\nreturn nil, err\n}\n}\n"
+	}
+	return src + "return json.Marshal(out)\n}\n\n"
+}
+
+// CatchAllMethods returns the JSON methods of a struct whose field collects
+// the properties that none of its other fields, with the given keys, declare.
+// The field is a map of mapType, or a json.RawMessage holding them as one
+// object. With omitEmpty, the other fields are encoded by marshalFields
+// instead of directly.
+func (g *Generator) CatchAllMethods(name, unmarshal, field, mapType string, keys []string, omitEmpty bool) string {
+	g.imports["encoding/json"] = true
+	raw := mapType == "json.RawMessage"
+	keyType, valueType := "string", "json.RawMessage"
+	if !raw {
+		keyType = mapType[len("map["):strings.Index(mapType, "]")]
+		valueType = mapType[strings.Index(mapType, "]")+1:]
+	}
+
+	marshal := "type plain " + name + "\ndata, err := json.Marshal(plain(x))\n"
+	if omitEmpty {
+		marshal = "data, err := x.marshalFields()\n"
+	}
+	known := make([]string, len(keys))
+	for i, key := range keys {
+		known[i] = strconv.Quote(key)
+	}
+
+	// Raw fields are decoded first, so values that aren't objects fail and
+	// empty ones are left out.
+	extra := "extra, err := json.Marshal(x." + field + ")\n"
+	if raw {
+		extra = "var fields map[string]json.RawMessage\n" +
+			"if err := json.Unmarshal(x." + field + ", &fields); err != nil {\nreturn nil, err\n}\n" +
+			"if len(fields) == 0 {\nreturn data, nil\n}\n" +
+			"extra, err := json.Marshal(fields)\n"
+	}
+	collect := "x." + field + " = nil\n" +
+		"for key, raw := range extra {\n" +
+		"var value " + valueType + "\n" +
+		"if err := json.Unmarshal(raw, &value); err != nil {\nreturn err\n}\n" +
+		"if x." + field + " == nil {\nx." + field + " = make(" + mapType + ", len(extra))\n}\n" +
+		"x." + field + "[key] = value\n}\n" +
+		"return nil\n"
+	if raw {
+		collect = "x." + field + " = nil\n" +
+			"if len(extra) == 0 {\nreturn nil\n}\n" +
+			"var err error\n" +
+			"x." + field + ", err = json.Marshal(extra)\n" +
+			"return err\n"
+	}
+
+	return "func (x " + name + ") MarshalJSON() ([]byte, error) {\n" +
+		marshal +
+		"if err != nil || len(x." + field + ") == 0 {\nreturn data, err\n}\n" +
+		extra +
+		"if err != nil {\nreturn nil, err\n}\n" +
+		"if len(data) == 2 {\nreturn extra, nil\n}\n" +
+		"return append(append(data[:len(data)-1], ','), extra[1:]...), nil\n}\n\n" +
+		"func (x *" + name + ") " + unmarshal + "(data []byte) error {\n" +
+		"type plain " + name + "\n" +
+		"if err := json.Unmarshal(data, (*plain)(x)); err != nil {\nreturn err\n}\n" +
+		"var extra map[" + keyType + "]json.RawMessage\n" +
+		"if err := json.Unmarshal(data, &extra); err != nil {\nreturn err\n}\n" +
+		"for _, key := range []" + keyType + "{" + strings.Join(known, ", ") + "} {\ndelete(extra, key)\n}\n" +
+		collect +
+		"}\n\n"
+}
+
+func (g *Generator) TupleType(js *JsonSchema, path string) (string, error) {
+	src := "struct {\n"
+	var models []FieldModel
+	elems := make([]string, len(js.ItemsList))
+	for i, item := range js.ItemsList {
+		typ, err := g.GoType(item, !g.NoCollapse, path+"Elem"+strconv.Itoa(i))
+		if err != nil {
+			return "", err
+		}
+		elems[i] = "Elem" + strconv.Itoa(i)
+		comment := g.Comment(elems[i], item.Doc())
+		src += comment + elems[i] + " " + typ + "\n"
+		models = append(models, FieldModel{Name: elems[i], Type: typ, Doc: CommentText(comment)})
+	}
+	var rest string
+	if js.AdditionalItems != nil {
+		var err error
+		if rest, err = g.GoType(js.AdditionalItems, !g.NoCollapse, path+"Rest"); err != nil {
+			return "", err
+		}
+	} else if allowed, ok := js.AdditionalItemsValue.(bool); ok && allowed {
+		rest = "interface{}"
+	}
+	var comment string
+	if js.AdditionalItems != nil {
+		comment = g.Comment("Rest", js.AdditionalItems.Doc())
+		src += comment
+	}
+	if len(rest) > 0 {
+		src += "Rest []" + rest + "\n"
+		models = append(models, FieldModel{Name: "Rest", Type: "[]" + rest, Doc: CommentText(comment)})
+	}
+	src += "}"
+	g.structs[src] = models
+
+	name := path
+	if len(name) == 0 {
+		name = "Tuple"
+	}
+	name, err := g.ClaimName(name, path, func(name string) bool {
+		return g.types[g.TypeName(name)] == src
+	})
+	if err != nil {
+		return "", err
+	}
+	name = g.TypeName(name)
+	g.imports["encoding/json"] = true
+	if len(rest) == 0 {
+		g.imports["fmt"] = true
+	}
+	g.types[name] = src
+	g.docs[name] = js.Doc()
+	if len(g.docs[name]) == 0 {
+		g.docs[name] = "is encoded as a JSON array of " + strconv.Itoa(len(elems)) + " items."
+		if len(rest) > 0 {
+			g.docs[name] = "is encoded as a JSON array of " + strconv.Itoa(len(elems)) + " items followed by the Rest items."
+		}
+	}
+
+	n := strconv.Itoa(len(elems))
+	methods := "func (t " + name + ") MarshalJSON() ([]byte, error) {\n"
+	if len(rest) > 0 {
+		methods += "elems := []interface{}{t." + strings.Join(elems, ", t.") + "}\n"
+		methods += "for _, v := range t.Rest {\nelems = append(elems, v)\n}\n"
+		methods += "return json.Marshal(elems)\n}\n\n"
+	} else {
+		methods += "return json.Marshal([]interface{}{t." + strings.Join(elems, ", t.") + "})\n}\n\n"
+	}
+	methods += "func (t *" + name + ") UnmarshalJSON(data []byte) error {\n"
+	methods += "var elems []json.RawMessage\n"
+	methods += "if err := json.Unmarshal(data, &elems); err != nil {\nreturn err\n}\n"
+	if len(rest) == 0 {
+		methods += "if len(elems) > " + n + " {\n"
+		methods += "return fmt.Errorf(\"expected at most " + n + " tuple items, got %d\", len(elems))\n}\n"
+	}
+	for i, elem := range elems {
+		methods += "if len(elems) > " + strconv.Itoa(i) + " {\n"
+		methods += "if err := json.Unmarshal(elems[" + strconv.Itoa(i) + "], &t." + elem + "); err != nil {\nreturn err\n}\n}\n"
+	}
+	if len(rest) > 0 {
+		methods += "t.Rest = nil\n"
+		methods += "if len(elems) > " + n + " {\n"
+		methods += "t.Rest = make([]" + rest + ", len(elems)-" + n + ")\n"
+		methods += "for i, elem := range elems[" + n + ":] {\n"
+		methods += "if err := json.Unmarshal(elem, &t.Rest[i]); err != nil {\nreturn err\n}\n}\n}\n"
+	}
+	methods += "return nil\n}\n\n"
+	g.methods[name] = methods
+	return name, nil
+}
+
+func (g *Generator) LoadRef(js *JsonSchema) (err error) {
+	depth := len(g.loading)
+	defer func() { g.loading = g.loading[:depth] }()
+	if key := g.DocumentKey(js); len(key.ref) > 0 && len(js.Ref) > 0 {
+		g.loading = append(g.loading, key)
+	}
+	for len(js.Ref) > 0 {
+		key := js.RefKey()
+		g.loading = append(g.loading, key)
+		for i, loading := range g.loading[:len(g.loading)-1] {
+			if loading == key {
+				var refs []string
+				for _, loading := range g.loading[i:] {
+					refs = append(refs, loading.ref)
+				}
+				return CycleError(refs)
+			}
+		}
+
+		ref := js.Ref
+		js.Ref = ""
+		if err = g.LoadRefInto(ref, js); err != nil {
+			return
+		}
+	}
+	if js.Properties == nil {
+		js.Properties = make(map[string]*JsonSchema)
+	}
+	if err = js.ParseAdditional(); err != nil {
+		return
+	}
+
+	if js.Extends != nil {
+		if err = g.LoadRef(js.Extends); err != nil {
+			return
+		}
+		g.Inherit(js, js.Extends)
+	}
+	for _, member := range js.AllOf {
+		if err = g.LoadRef(member); err != nil {
+			return
+		}
+		g.Inherit(js, member)
+	}
+	for _, member := range js.AnyOf {
+		if err = g.LoadRef(member); err != nil {
+			return
+		}
+		g.Alternate(js, member)
+	}
+	if g.Conditionals {
+		// The branches only apply when if does, so their properties are
+		// folded in as optional fields and their required lists are ignored.
+		for _, branch := range []*JsonSchema{js.Then, js.Else} {
+			if branch == nil {
+				continue
+			}
+			if err = g.LoadRef(branch); err != nil {
+				return
+			}
+			if js.conditional == nil {
+				js.conditional = make(map[string]bool)
+			}
+			for k := range branch.Properties {
+				if _, ok := js.Properties[k]; !ok {
+					js.conditional[k] = true
+				}
+			}
+			g.Alternate(js, branch)
+		}
+	}
+	return
+}
+
+// Alternate merges the properties of a schema that may or may not apply into
+// js. Properties declared by several alternatives with different types end up
+// as interface{}.
+func (g *Generator) Alternate(js *JsonSchema, member *JsonSchema) {
+	if js.Type == nil {
+		js.Type = member.Type
+	}
+
+	if js.alternatives == nil {
+		js.alternatives = make(map[string][]*JsonSchema)
+	}
+	for k, v := range member.Properties {
+		if _, ok := js.Properties[k]; !ok {
+			js.Properties[k] = v
+			js.alternatives[k] = []*JsonSchema{v}
+		} else if alts, ok := js.alternatives[k]; ok && !ContainsSchema(alts, v) {
+			js.alternatives[k] = append(alts, v)
+		}
+	}
+}
+
+func ContainsSchema(list []*JsonSchema, schema *JsonSchema) bool {
+	for _, v := range list {
+		if v == schema {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generator) CommonType(schemas []*JsonSchema, path string) (string, error) {
+	var common string
+	for i, schema := range schemas {
+		typ, err := g.GoType(schema, !g.NoCollapse, path)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 && typ != common {
+			return g.Fallback(path, "alternatives have different types"), nil
+		}
+		common = typ
+	}
+	return common, nil
+}
+
+func (g *Generator) EmbeddedParents(js *JsonSchema) (parents []*JsonSchema) {
+	if !g.Embed {
+		return
+	}
+	if js.Extends != nil && g.IsEmbeddable(js.Extends) {
+		parents = append(parents, js.Extends)
+	}
+	for _, member := range js.AllOf {
+		if g.IsEmbeddable(member) {
+			parents = append(parents, member)
+		}
+	}
+	return
+}
+
+func (g *Generator) IsEmbeddable(parent *JsonSchema) bool {
+	return g.Embed && len(parent.Title) > 0 && len(parent.Properties) > 0
+}
+
+func (g *Generator) Inherit(js *JsonSchema, parent *JsonSchema) {
+	if g.IsEmbeddable(parent) {
+		if js.Type == nil {
+			js.Type = parent.Type
+		}
+		return
+	}
+	if len(js.Title) == 0 {
+		js.Title = parent.Title
+	}
+	if js.Type == nil {
+		js.Type = parent.Type
+	}
+	if js.Items == nil {
+		js.Items = parent.Items
+	}
+	if js.ItemsList == nil {
+		js.ItemsList = parent.ItemsList
+	}
+	for k, v := range parent.Properties {
+		if _, ok := js.Properties[k]; !ok {
+			js.Properties[k] = v
+		}
+	}
+	// Copies of a schema made while resolving refs share Required, so it is
+	// reallocated rather than appended to in place.
+	for _, n := range parent.Required {
+		if !js.IsRequired(n) {
+			js.Required = append(js.Required[:len(js.Required):len(js.Required)], n)
+		}
+	}
+}
+
+// InferredType returns the type implied by a schema that leaves it out: an
+// object if it has properties or additionalProperties, an array if it has
+// items, and otherwise any.
+func (js *JsonSchema) InferredType() string {
+	switch {
+	case len(js.Properties) > 0 || len(js.PatternProperties) > 0 || js.AdditionalInterface != nil:
+		return "object"
+	case js.Items != nil || len(js.ItemsList) > 0:
+		return "array"
+	}
+	return "any"
+}
+
+func (js *JsonSchema) IsRequired(name string) bool {
+	for _, n := range js.Required {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RefKey identifies the schema js.Ref points to. Fragment-only refs are
+// relative to the document js belongs to.
+func (js *JsonSchema) RefKey() refKey {
+	if len(js.Ref) == 0 {
+		return refKey{}
+	}
+	if strings.HasPrefix(js.Ref, "#") {
+		return refKey{js.root, js.Ref}
+	}
+	return refKey{nil, ResolveURI(js.base, js.Ref)}
+}
+
+// DocumentKey is the key refs to the document js is the root of use, so
+// a cycle can be reported from the file it starts at. It is empty for
+// schemas that aren't the root of a file.
+func (g *Generator) DocumentKey(js *JsonSchema) refKey {
+	if js.root != js || len(js.path) == 0 {
+		return refKey{}
+	}
+	// With BaseDir, refs to local files aren't resolved, so they are
+	// relative to it like the paths ReadRef reads.
+	if len(g.BaseDir) > 0 && filepath.IsAbs(js.path) {
+		base, err := filepath.Abs(g.BaseDir)
+		if err != nil {
+			return refKey{nil, js.path}
+		}
+		if rel, err := filepath.Rel(base, js.path); err == nil && !strings.HasPrefix(rel, "..") {
+			return refKey{nil, rel}
+		}
+	}
+	return refKey{nil, js.path}
+}
+
+// Origin returns the schema js was copied from, so that copies made for
+// refs are known to be the same schema.
+func (js *JsonSchema) Origin() *JsonSchema {
+	if js.origin != nil {
+		return js.origin
+	}
+	return js
+}
+
+func CycleError(refs []string) error {
+	return fmt.Errorf("Cyclic $ref: %s", strings.Join(refs, " -> "))
+}
+
+func (g *Generator) LoadRefInto(ref string, schema *JsonSchema) error {
+	path, fragment := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		path, fragment = ref[:i], ref[i+1:]
+	}
+
+	g.Logf("Loading ref %s", ref)
+	into := &JsonSchema{}
+	if len(fragment) == 0 {
+		into = schema
+	}
+	root, err := g.RefDocument(path, schema, into)
+	if err != nil {
+		return err
+	}
+	if root == into && into == schema {
+		return nil
+	}
+	if root == nil {
+		return errors.New("Ref has no root schema: " + ref)
+	}
+
+	target := root.Resolve(fragment)
+	if target == nil {
+		return errors.New("Ref not found: " + ref)
+	}
+	if err := g.LoadRef(target); err != nil {
+		return err
+	}
+
+	title := schema.Title
+	*schema = *target
+	schema.origin = target.Origin()
+	if len(schema.Title) == 0 {
+		schema.Title = title
+	}
+	return nil
+}
+
+// RefDocument returns the document that the path of a ref in schema points
+// to, reading it into into unless it is schema's own document or one with a
+// matching $id.
+func (g *Generator) RefDocument(path string, schema, into *JsonSchema) (*JsonSchema, error) {
+	if len(path) == 0 {
+		return schema.root, nil
+	}
+	path = ResolveURI(schema.base, path)
+	if doc := schema.root.FindID(path); doc != nil {
+		return doc, nil
+	}
+	g.Logf("Reading %s", path)
+	if err := g.ReadSchema(path, into); err != nil {
+		return nil, err
+	}
+	into.SetRoot(into)
+	into.path = path
+	// Refs in a local file are relative to the file, unless BaseDir says
+	// where all of them are.
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") || len(g.BaseDir) == 0 {
+		into.SetBase(path)
+	} else {
+		into.SetBase("")
+	}
+	return into, nil
+}
+
+func (g *Generator) ReadSchema(path string, schema *JsonSchema) error {
+	file, err := g.ReadRef(path)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(file, schema)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func (g *Generator) ReadRef(path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(g.BaseDir, path)
+		}
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.New("Ref not found: " + path)
+		}
+		return file, nil
+	}
+
+	if g.NoRemote {
+		return nil, errors.New("Remote ref not allowed: " + path)
+	}
+	if file, ok := g.remoteCache[path]; ok {
+		return file, nil
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ref not found: %s (%s)", path, resp.Status)
+	}
+	file, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	g.remoteCache[path] = file
+	return file, nil
+}
+
+func (js *JsonSchema) AllDefinitions() map[string]*JsonSchema {
+	if len(js.Defs) == 0 {
+		return js.Definitions
+	}
+	defs := make(map[string]*JsonSchema)
+	for name, def := range js.Definitions {
+		defs[name] = def
+	}
+	for name, def := range js.Defs {
+		defs[name] = def
+	}
+	return defs
+}
+
+// Resolve follows a JSON Pointer from js through the keywords that hold
+// schemas, such as /definitions/Address/properties/zip or /items/0.
+func (js *JsonSchema) Resolve(pointer string) *JsonSchema {
+	if len(pointer) == 0 || pointer == "/" {
+		return js
+	}
+
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	cur := js
+	for i := 0; i < len(tokens); i++ {
+		var next *JsonSchema
+		switch tokens[i] {
+		case "definitions", "$defs", "properties", "patternProperties":
+			schemas := map[string]map[string]*JsonSchema{
+				"definitions":       cur.AllDefinitions(),
+				"$defs":             cur.AllDefinitions(),
+				"properties":        cur.Properties,
+				"patternProperties": cur.PatternProperties,
+			}[tokens[i]]
+			if i++; i < len(tokens) {
+				next = schemas[tokens[i]]
+			}
+		case "items", "allOf", "anyOf", "oneOf":
+			if tokens[i] == "items" && cur.Items != nil {
+				next = cur.Items
+				break
+			}
+			schemas := map[string][]*JsonSchema{
+				"items": cur.ItemsList,
+				"allOf": cur.AllOf,
+				"anyOf": cur.AnyOf,
+				"oneOf": cur.OneOf,
+			}[tokens[i]]
+			if i++; i < len(tokens) {
+				if n, err := strconv.Atoi(tokens[i]); err == nil && n >= 0 && n < len(schemas) {
+					next = schemas[n]
+				}
+			}
+		case "extends":
+			next = cur.Extends
+		case "propertyNames":
+			next = cur.PropertyNames
+		case "contains":
+			next = cur.Contains
+		case "if":
+			next = cur.If
+		case "then":
+			next = cur.Then
+		case "else":
+			next = cur.Else
+		}
+		if next == nil {
+			return nil
+		}
+		cur = next
+	}
+	return cur
+}
+
+func (js *JsonSchema) Children() []*JsonSchema {
+	children := []*JsonSchema{js.Extends, js.Items, js.AdditionalProperties, js.AdditionalItems, js.PropertyNames, js.Contains}
+	children = append(children, js.ItemsList...)
+	children = append(children, js.OneOf...)
+	children = append(children, js.AllOf...)
+	children = append(children, js.AnyOf...)
+	children = append(children, js.If, js.Then, js.Else)
+	for _, m := range []map[string]*JsonSchema{js.Properties, js.PatternProperties, js.Definitions, js.Defs} {
+		for _, v := range m {
+			children = append(children, v)
+		}
+	}
+	return children
+}
+
+func (js *JsonSchema) SetRoot(root *JsonSchema) {
+	if js == nil {
+		return
+	}
+	js.root = root
+	for _, v := range js.Children() {
+		v.SetRoot(root)
+	}
+}
+
+func (js *JsonSchema) SetBase(base string) {
+	if js == nil {
+		return
+	}
+	js.base = js.BaseURI(base)
+	for _, v := range js.Children() {
+		v.SetBase(js.base)
+	}
+}
+
+// BaseURI returns the URI that refs in js are resolved against, given the
+// base URI of its parent.
+func (js *JsonSchema) BaseURI(base string) string {
+	if len(js.ID) == 0 || strings.HasPrefix(js.ID, "#") {
+		return base
+	}
+	return strings.TrimSuffix(ResolveURI(base, js.ID), "#")
+}
+
+func (js *JsonSchema) FindID(uri string) *JsonSchema {
+	if js == nil {
+		return nil
+	}
+	if len(js.ID) > 0 && js.base == uri {
+		return js
+	}
+	for _, v := range js.Children() {
+		if found := v.FindID(uri); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func ResolveURI(base, ref string) string {
+	if len(base) == 0 {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil || refURL.IsAbs() {
+		return ref
+	}
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		// Bases that aren't URLs are the paths of local files, and an $id
+		// ending in a slash names a directory.
+		if filepath.IsAbs(ref) {
+			return ref
+		}
+		path := filepath.Join(filepath.Dir(base), ref)
+		if strings.HasSuffix(ref, "/") {
+			path += "/"
+		}
+		return path
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// NullableType returns typ as a pointer, or wrapped in the generic Optional
+// type with Generics.
+func (g *Generator) NullableType(typ string) string {
+	if !g.Generics {
+		return "*" + typ
+	}
+	g.optional = true
+	g.imports["encoding/json"] = true
+	return g.TypeName("Optional") + "[" + typ + "]"
+}
+
+func (g *Generator) OptionalElem(typ string) (string, bool) {
+	prefix := g.TypeName("Optional") + "["
+	if !g.Generics || !strings.HasPrefix(typ, prefix) || !strings.HasSuffix(typ, "]") {
+		return typ, false
+	}
+	return typ[len(prefix) : len(typ)-1], true
+}
+
+func (g *Generator) OptionalLiteral(typ, value string) string {
+	return typ + "{Value: " + value + ", Valid: true}"
+}
+
+func (g *Generator) OptionalSource() string {
+	if !g.optional {
+		return ""
+	}
+	name := g.TypeName("Optional")
+	return g.Comment(name, "holds a value that may be absent or null. It encodes as null when Valid is false.") +
+		"type " + name + `[T any] struct {
+	Value T
+	Valid bool
+}
+
+func (o ` + name + `[T]) MarshalJSON() ([]byte, error) {
+	if !o.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+func (o *` + name + `[T]) UnmarshalJSON(data []byte) error {
+	*o = ` + name + `[T]{}
+	if string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, &o.Value); err != nil {
+		return err
+	}
+	o.Valid = true
+	return nil
+}
+
+`
+}
+
+func (g *Generator) IsNilable(typ string) bool {
+	return typ == "interface{}" || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || strings.HasPrefix(typ, "*") ||
+		strings.HasPrefix(g.types[typ], "interface")
+}
+
+func UniqueName(name string, used map[string]bool) string {
+	if len(name) == 0 {
+		name = "Field"
+	}
+
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+func StructTag(tags []string) string {
+	tag := strings.Join(tags, " ")
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// Doc returns the description used for doc comments, as a Deprecated: comment
+// if the schema is deprecated.
+func (js *JsonSchema) Doc() string {
+	if !js.Deprecated {
+		return js.Description
+	}
+	if description := strings.TrimSpace(js.Description); len(description) > 0 {
+		return "Deprecated: " + description
+	}
+	return "Deprecated: Do not use."
+}
+
+func (g *Generator) Comment(name, text string) (out string) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 || g.NoComments {
+		return
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		if i == 0 && !strings.HasPrefix(line, "Deprecated:") {
+			line = name + " " + line
+		}
+		line = strings.TrimRight("// "+line, " \t\r")
+		if g.CommentWidth <= 0 || len(line) <= g.CommentWidth {
+			out += line + "\n"
+			continue
+		}
+
+		wrapped := "//"
+		for _, word := range strings.Fields(line[2:]) {
+			if len(wrapped) > 2 && len(wrapped)+1+len(word) > g.CommentWidth {
+				out += wrapped + "\n"
+				wrapped = "//"
+			}
+			wrapped += " " + word
+		}
+		out += wrapped + "\n"
+	}
+	return
+}
+
+func SortedKeys(in interface{}) []string {
+	keysReflect := reflect.ValueOf(in).MapKeys()
+	keys := make([]string, len(keysReflect))
+	for i, k := range keysReflect {
+		keys[i] = k.String()
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var Initialisms = map[string]bool{
+	"API":   true,
+	"HTTP":  true,
+	"HTTPS": true,
+	"ID":    true,
+	"JSON":  true,
+	"SQL":   true,
+	"URI":   true,
+	"URL":   true,
+	"UUID":  true,
+	"XML":   true,
+}
+
+func (g *Generator) Capitalize(in string) (out string) {
+	words := strings.FieldsFunc(in, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if upper := strings.ToUpper(word); g.initialisms[upper] {
+			out += upper
+		} else {
+			r, size := utf8.DecodeRuneInString(word)
+			out += string(unicode.ToUpper(r)) + word[size:]
+		}
+	}
+	return sanitizeIdentifier(out, in)
+}
+
+// sanitizeIdentifier makes sure a capitalized name is an exported Go
+// identifier, falling back to a name derived from the original text when
+// nothing usable is left.
+func sanitizeIdentifier(name, original string) string {
+	if len(name) == 0 {
+		if len(strings.Trim(original, " _-")) == 0 {
+			return ""
+		}
+		return "X" + hex.EncodeToString([]byte(original))
+	}
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsUpper(r) {
+		return "X" + name
+	}
+	return name
+}
+
+func (g *Generator) JSONKey(name string) string {
+	if g.Camel {
+		return LowerCamel(name)
+	}
+	return name
+}
+
+// JSONKeys returns the keys the properties of js are encoded with. A key
+// that -camel would turn into the key of another property is kept as it is.
+func (g *Generator) JSONKeys(js *JsonSchema, path string) (map[string]string, error) {
+	names := g.PropertyNames(js)
+	keys := make(map[string]string, len(names))
+	used := make(map[string]string, len(names))
+	// Keys that don't change are taken first.
+	for _, n := range names {
+		if g.JSONKey(n) == n {
+			keys[n], used[n] = n, n
+		}
+	}
+	for _, n := range names {
+		if _, ok := keys[n]; ok {
+			continue
+		}
+		key := g.JSONKey(n)
+		if other, ok := used[key]; ok {
+			if len(path) == 0 {
+				path = "schema"
+			}
+			err := fmt.Errorf("%s: properties %s and %s would both be encoded as %s", path, other, n, key)
+			if g.Strict {
+				return nil, err
+			}
+			g.Warn(err.Error() + ", so " + n + " keeps its key")
+			key = n
+		}
+		keys[n], used[key] = key, n
+	}
+	return keys, nil
+}
+
+func LowerCamel(in string) string {
+	trimmed := strings.TrimLeft(in, "_")
+	out := in[:len(in)-len(trimmed)]
+	for i, word := range strings.FieldsFunc(trimmed, func(r rune) bool { return r == '_' }) {
+		if i == 0 {
+			out += word
+		} else {
+			out += strings.ToUpper(word[0:1]) + word[1:]
+		}
+	}
+	return out
+}
+
+func ConstName(value interface{}) (out string) {
+	str := fmt.Sprint(value)
+	if strings.HasPrefix(str, "-") {
+		str = "Neg" + str[1:]
+	}
+	for _, word := range strings.FieldsFunc(str, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		out += strings.ToUpper(word[0:1]) + word[1:]
+	}
+	return
+}
+
+type Options struct {
+	PackageName     string
+	StructPrefix    string
+	StructSuffix    string
+	SourceName      string
+	BaseDir         string
+	UniqueItems     string
+	IntType         string
+	CommentWidth    int
+	NumberType      string
+	CatchAllField   string
+	CatchAllType    string
+	Template        string
+	Initialisms     []string
+	Log             io.Writer
+	OmitEmpty       bool
+	DeepOmitEmpty   bool
+	Pointers        bool
+	PreserveOrder   bool
+	YAMLTags        bool
+	NoRemote        bool
+	Dedup           bool
+	Validators      bool
+	Stringer        bool
+	Limits          bool
+	SplitRW         bool
+	Embed           bool
+	Accessors       bool
+	StrictUnmarshal bool
+	MapKeys         bool
+	Camel           bool
+	Strict          bool
+	DeepCopy        bool
+	Equal           bool
+	IsZero          bool
+	NamespaceTitles bool
+	LooseKeys       bool
+	GenTests        bool
+	Generics        bool
+	Conditionals    bool
+	NoCollapse      bool
+	CollapseAll     bool
+	CatchAll        bool
+	Registry        bool
+	NoComments      bool
+	NoHeader        bool
+	NoFormat        bool
+}
+
+func NewGenerator(opts Options) *Generator {
+	g := &Generator{
+		Options:     opts,
+		remoteCache: make(map[string][]byte),
+		initialisms: make(map[string]bool),
+	}
+	for word := range Initialisms {
+		g.initialisms[word] = true
+	}
+	if len(g.IntType) == 0 {
+		g.IntType = "int64"
+	}
+	if len(g.NumberType) == 0 {
+		g.NumberType = "float64"
+	}
+	if len(g.CatchAllField) == 0 {
+		g.CatchAllField = "AdditionalProperties"
+	}
+	for _, word := range opts.Initialisms {
+		if word = strings.TrimSpace(word); len(word) > 0 {
+			g.initialisms[strings.ToUpper(word)] = true
+		}
+	}
+	return g
+}
+
+func Load(ref string, opts Options) (*JsonSchema, error) {
+	return NewGenerator(opts).Load(ref)
+}
+
+func (g *Generator) Load(ref string) (*JsonSchema, error) {
+	schema := &JsonSchema{}
+	if err := g.LoadRefInto(ref, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func Read(r io.Reader) (*JsonSchema, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	schema := &JsonSchema{}
+	if err = json.Unmarshal(data, schema); err != nil {
+		return nil, err
+	}
+	schema.SetRoot(schema)
+	schema.SetBase("")
+	return schema, nil
+}
+
+func (g *Generator) Source() ([]byte, error) {
+	var src bytes.Buffer
+	fmt.Fprint(&src, g.PatternVars())
+	fmt.Fprint(&src, g.RegistryVar())
+	fmt.Fprint(&src, g.OptionalSource())
+	for _, name := range SortedKeys(g.types) {
+		typeSrc, err := g.TypeSource(name)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(&src, typeSrc)
+	}
+
+	// Checks of inlined structs are dropped, so imports only they needed are
+	// left out.
+	header := g.FileHeader(g.PackageName, g.UsedImports(src.String()))
+	return g.FormatSource(append([]byte(header), src.Bytes()...))
+}
+
+func (g *Generator) RegistryVar() string {
+	if !g.Registry || len(g.types) == 0 {
+		return ""
+	}
+
+	src := "var TypeRegistry = map[string]reflect.Type{\n"
+	for _, name := range SortedKeys(g.types) {
+		key := strings.TrimSuffix(strings.TrimPrefix(name, g.StructPrefix), g.StructSuffix)
+		src += strconv.Quote(key) + ": reflect.TypeOf((*" + name + ")(nil)).Elem(),\n"
+	}
+	return src + "}\n\n"
+}
+
+func (g *Generator) FormatSource(src []byte) ([]byte, error) {
+	if g.NoFormat {
+		return src, nil
+	}
+	srcFmt, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("Generated invalid source (use -no-format to inspect it): %v", err)
+	}
+	return srcFmt, nil
+}
+
+func (g *Generator) FileHeader(pkg string, imports []string) string {
+	var src bytes.Buffer
+	if !g.NoHeader {
+		if len(g.SourceName) > 0 {
+			fmt.Fprintf(&src, "// Code generated by json-structgen from %s; DO NOT EDIT.\n\n", g.SourceName)
+		} else {
+			fmt.Fprint(&src, "// Code generated by json-structgen; DO NOT EDIT.\n\n")
+		}
+	}
+	if len(pkg) > 0 {
+		fmt.Fprintln(&src, "package", pkg)
+		fmt.Fprintln(&src)
+	}
+
+	if len(imports) > 0 {
+		fmt.Fprintln(&src, "import (")
+		for _, path := range imports {
+			fmt.Fprintf(&src, "\t%q\n", path)
+		}
+		fmt.Fprintln(&src, ")")
+		fmt.Fprintln(&src)
+	}
+	return src.String()
+}
+
+// WriteFileAtomic replaces the file at path with data through a temporary
+// file, so readers never see it half-written. The file keeps its mode, and
+// new files are created with 0644.
+func WriteFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func Generate(opts Options, schemas ...*JsonSchema) (string, error) {
+	return NewGenerator(opts).Generate(schemas...)
+}
+
+func (g *Generator) Generate(schemas ...*JsonSchema) (string, error) {
+	g.types = make(map[string]string)
+	g.consts = make(map[string]string)
+	g.imports = make(map[string]bool)
+	g.methods = make(map[string]string)
+	g.ownMethods = make(map[string]string)
+	g.docs = make(map[string]string)
+	g.inProgress = make(map[string]bool)
+	g.recursive = make(map[string]bool)
+	g.cuts = nil
+	g.anon = make(map[string]*AnonType)
+	g.memo = make(map[memoKey]string)
+	g.fields = make(map[*JsonSchema][]structField)
+	g.structs = make(map[string][]FieldModel)
+	g.variants = make(map[string][]string)
+	g.schemas = make(map[string]*JsonSchema)
+	g.examples = make(map[string][]string)
+	g.mismatched = make(map[string][]string)
+	g.validations = make(map[string][]string)
+	g.inline = make(map[*JsonSchema][]string)
+	g.patterns = make(map[string]string)
+	g.optional = false
+	g.Warnings = nil
+	if !intTypes[g.IntType] {
+		return "", fmt.Errorf("Unsupported integer type: %s", g.IntType)
+	}
+	if !numberTypes[g.NumberType] {
+		return "", fmt.Errorf("Unsupported number type: %s", g.NumberType)
+	}
+	if !uniqueItemsModes[g.UniqueItems] {
+		return "", fmt.Errorf("Unsupported uniqueItems mode: %s", g.UniqueItems)
+	}
+	if len(g.CatchAllType) > 0 && !catchAllTypes[g.CatchAllType] {
+		return "", fmt.Errorf("Unsupported catch-all type: %s", g.CatchAllType)
+	}
+	if !token.IsIdentifier(g.CatchAllField) || !token.IsExported(g.CatchAllField) {
+		return "", fmt.Errorf("Invalid catch-all field %q: expected an exported Go identifier", g.CatchAllField)
+	}
+	if err := g.ParseTemplate(); err != nil {
+		return "", err
+	}
+
+	for i, schema := range schemas {
+		previous := make(map[string]string, len(g.types))
+		for name, src := range g.types {
+			previous[name] = src
+		}
+
+		defs := schema.AllDefinitions()
+		for name, def := range defs {
+			def.Title = name
+		}
+
+		if _, err := g.GoType(schema, true, ""); err != nil {
+			return "", err
+		}
+		if g.NoCollapse {
+			// Definitions are inlined where they are used, like any other
+			// nested schema.
+			defs = nil
+		}
+		for _, name := range SortedKeys(defs) {
+			typ, err := g.GoType(defs[name], true, g.Capitalize(name))
+			if err != nil {
+				return "", err
+			}
+			if typeName := g.TypeName(g.Capitalize(name)); typ != typeName {
+				if _, err := g.ClaimName(g.Capitalize(name), g.Capitalize(name), func(name string) bool {
+					return g.types[g.TypeName(name)] == typ
+				}); err != nil {
+					return "", err
+				}
+				g.types[typeName] = typ
+				g.docs[typeName] = defs[name].Doc()
+			}
+		}
+
+		for _, name := range SortedKeys(g.types) {
+			if _, ok := previous[name]; !ok {
+				g.Logf("Input %d: registered type %s", i+1, name)
+			}
+		}
+		for _, name := range SortedKeys(previous) {
+			if g.types[name] != previous[name] {
+				return "", fmt.Errorf("Type %s from input %d conflicts with an earlier input", name, i+1)
+			}
+		}
+	}
+
+	g.HoistAnonTypes()
+	if g.Registry && len(g.types) > 0 {
+		g.imports["reflect"] = true
+	}
+	src, err := g.Source()
+	return string(src), err
+}
@@ -0,0 +1,1001 @@
+package structgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Update golden files")
+
+var goldenTests = []struct {
+	name string
+	opts Options
+}{
+	{name: "simple"},
+	{name: "nested"},
+	{name: "array"},
+	{name: "map"},
+	{name: "nested_additional"},
+	{name: "preserve_order", opts: Options{PreserveOrder: true}},
+	{name: "map_keys", opts: Options{MapKeys: true}},
+	{name: "bool_schemas"},
+	{name: "patterns"},
+	{name: "closed"},
+	{name: "strict_unmarshal", opts: Options{PackageName: "golden", StrictUnmarshal: true}},
+	{name: "catch_all", opts: Options{PackageName: "golden", CatchAll: true, DeepOmitEmpty: true, YAMLTags: true}},
+	{name: "catch_all_raw", opts: Options{PackageName: "golden", CatchAll: true, CatchAllField: "Unknown", CatchAllType: "json.RawMessage", YAMLTags: true}},
+	{name: "extends"},
+	{name: "extends_required", opts: Options{OmitEmpty: true, Pointers: true}},
+	{name: "embed", opts: Options{Embed: true}},
+	{name: "embed_allof", opts: Options{Embed: true, YAMLTags: true}},
+	{name: "required", opts: Options{OmitEmpty: true, Pointers: true}},
+	{name: "accessors", opts: Options{PackageName: "golden", Pointers: true, Accessors: true}},
+	{name: "generics", opts: Options{PackageName: "golden", Generics: true, Pointers: true, Validators: true, DeepCopy: true}},
+	{name: "definitions"},
+	{name: "defs"},
+	{name: "deep_ref"},
+	{name: "id", opts: Options{NoRemote: true}},
+	{name: "enum"},
+	{name: "enum_stringer", opts: Options{PackageName: "golden", Stringer: true}},
+	{name: "enum_collisions", opts: Options{PackageName: "golden", Stringer: true}},
+	{name: "oneof", opts: Options{PackageName: "golden"}},
+	{name: "oneof_discriminator", opts: Options{PackageName: "golden"}},
+	{name: "allof", opts: Options{OmitEmpty: true}},
+	{name: "anyof", opts: Options{Pointers: true}},
+	{name: "anyof_scalar"},
+	{name: "conditionals", opts: Options{Conditionals: true}},
+	{name: "comments"},
+	{name: "comments_wrap", opts: Options{CommentWidth: 60}},
+	{name: "deprecated", opts: Options{PackageName: "golden"}},
+	{name: "yaml", opts: Options{YAMLTags: true, OmitEmpty: true}},
+	{name: "recursive"},
+	{name: "recursive_generics", opts: Options{PackageName: "golden", Generics: true, Pointers: true}},
+	{name: "shared"},
+	{name: "dedup", opts: Options{Dedup: true}},
+	{name: "no_collapse", opts: Options{PackageName: "golden", NoCollapse: true, Validators: true}},
+	{name: "collapse_all", opts: Options{CollapseAll: true}},
+	{name: "collisions"},
+	{name: "namespace_titles", opts: Options{NamespaceTitles: true}},
+	{name: "suffix", opts: Options{StructPrefix: "API", StructSuffix: "DTO", UniqueItems: "set"}},
+	{name: "const"},
+	{name: "formats", opts: Options{PackageName: "golden"}},
+	{name: "number_types", opts: Options{PackageName: "golden", IntType: "int", NumberType: "json.Number", Validators: true}},
+	{name: "unique", opts: Options{PackageName: "golden", UniqueItems: "set"}},
+	{name: "unique_validate", opts: Options{PackageName: "golden", UniqueItems: "validate"}},
+	{name: "validators", opts: Options{PackageName: "golden", Validators: true, Pointers: true}},
+	{name: "multiple_of", opts: Options{PackageName: "golden", Validators: true, Pointers: true}},
+	{name: "inline_validators", opts: Options{PackageName: "golden", Validators: true, Pointers: true}},
+	{name: "inline_validators_dedup", opts: Options{PackageName: "golden", Validators: true, Dedup: true}},
+	{name: "property_names", opts: Options{PackageName: "golden", Validators: true, MapKeys: true, CatchAll: true}},
+	{name: "fixed_arrays", opts: Options{PackageName: "golden", Validators: true, OmitEmpty: true}},
+	{name: "limits", opts: Options{PackageName: "golden", Limits: true, Pointers: true}},
+	{name: "split_rw", opts: Options{SplitRW: true, OmitEmpty: true}},
+	{name: "defaults", opts: Options{PackageName: "golden", Pointers: true}},
+	{name: "examples", opts: Options{PackageName: "golden"}},
+	{name: "examples_pointers", opts: Options{PackageName: "golden", Pointers: true}},
+	{name: "nullable", opts: Options{OmitEmpty: true}},
+	{name: "type_null", opts: Options{}},
+	{name: "tuple", opts: Options{PackageName: "golden"}},
+	{name: "tuple_rest", opts: Options{PackageName: "golden"}},
+	{name: "tags", opts: Options{YAMLTags: true}},
+	{name: "camel", opts: Options{OmitEmpty: true, YAMLTags: true, Camel: true}},
+	{name: "custom", opts: Options{Pointers: true}},
+	{name: "go_name", opts: Options{YAMLTags: true}},
+	{name: "proto", opts: Options{Pointers: true}},
+	{name: "loose_keys", opts: Options{PackageName: "golden", LooseKeys: true, CatchAll: true, StrictUnmarshal: true}},
+	{name: "deep_omitempty", opts: Options{PackageName: "golden", OmitEmpty: true, DeepOmitEmpty: true}},
+	{name: "deepcopy", opts: Options{PackageName: "golden", Pointers: true, DeepCopy: true}},
+	{name: "deepcopy_inline", opts: Options{PackageName: "golden", Pointers: true, DeepCopy: true}},
+	{name: "equal", opts: Options{PackageName: "golden", Pointers: true, Equal: true}},
+	{name: "equal_custom", opts: Options{PackageName: "golden", Equal: true}},
+	{name: "iszero", opts: Options{PackageName: "golden", IsZero: true}},
+	{name: "iszero_custom", opts: Options{PackageName: "golden", IsZero: true}},
+	{name: "untyped", opts: Options{PackageName: "golden"}},
+	{name: "contains", opts: Options{PackageName: "golden", Validators: true}},
+	{name: "registry", opts: Options{PackageName: "golden", Registry: true}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, test := range goldenTests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := generateFile(filepath.Join("testdata", test.name+".schema.json"), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", test.name+".golden")
+			if *update {
+				if err = ioutil.WriteFile(golden, []byte(out), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != string(expected) {
+				t.Errorf("Output does not match %s:\n%s", golden, out)
+			}
+		})
+	}
+}
+
+func generateFile(path string, opts Options) (string, error) {
+	if len(opts.StructPrefix) == 0 {
+		opts.StructPrefix = "Json"
+	}
+	opts.BaseDir = filepath.Dir(path)
+	opts.SourceName = filepath.Base(path)
+
+	schema, err := Load(filepath.Base(path), opts)
+	if err != nil {
+		return "", err
+	}
+	return Generate(opts, schema)
+}
+
+func TestStructTag(t *testing.T) {
+	tests := []struct {
+		tags     []string
+		expected string
+	}{
+		{[]string{`json:"name"`}, "`json:\"name\"`"},
+		{[]string{`json:"name"`, `yaml:"name"`}, "`json:\"name\" yaml:\"name\"`"},
+		{[]string{"json:" + strconv.Quote(`say "hi"`)}, "`json:\"say \\\"hi\\\"\"`"},
+		{[]string{"json:" + strconv.Quote("back`tick")}, `"json:\"back` + "`" + `tick\""`},
+	}
+
+	for _, test := range tests {
+		if tag := StructTag(test.tags); tag != test.expected {
+			t.Errorf("StructTag(%q) = %s, expected %s", test.tags, tag, test.expected)
+		}
+	}
+}
+
+func TestRemoteRef(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"definitions": {"point": {"title": "point", "type": "object", "properties": {"x": {"type": "number"}}}}}`)
+	}))
+	defer server.Close()
+
+	ref := server.URL + "/common.json#/definitions/point"
+	schema := JsonSchema{
+		Title: "shape",
+		Type:  "object",
+		Properties: map[string]*JsonSchema{
+			"center": {Ref: ref},
+			"corner": {Ref: ref},
+		},
+	}
+
+	out, err := Generate(Options{StructPrefix: "Json"}, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Center JsonPoint") || !strings.Contains(out, "Corner JsonPoint") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request for cached ref, got %d", requests)
+	}
+
+	if _, err = Load(server.URL+"/other.json", Options{NoRemote: true}); err == nil {
+		t.Error("Expected -no-remote to reject remote ref")
+	}
+}
+
+func TestRemoteRelativeRef(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/schemas/user.json":
+			fmt.Fprint(w, `{"title": "user", "type": "object", "properties": {"address": {"$ref": "address.json"}}}`)
+		case "/schemas/address.json":
+			fmt.Fprint(w, `{"title": "address", "type": "object", "properties": {"city": {"type": "string"}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	schema := JsonSchema{
+		ID:         server.URL + "/schemas/",
+		Title:      "account",
+		Type:       "object",
+		Properties: map[string]*JsonSchema{"owner": {Ref: "user.json"}},
+	}
+	schema.SetRoot(&schema)
+	schema.SetBase("")
+
+	out, err := Generate(Options{StructPrefix: "Json"}, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Owner JsonUser") || !strings.Contains(out, "Address JsonAddress") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+}
+
+func TestCyclicRef(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.json": `{"$ref": "b.json"}`,
+		"b.json": `{"$ref": "a.json"}`,
+		"c.json": `{"type": "object", "properties": {"next": {"$ref": "d.json"}}}`,
+		"d.json": `{"type": "object", "properties": {"next": {"$ref": "c.json"}}}`,
+		"e.json": `{"title": "e", "type": "object", "properties": {"next": {"$ref": "f.json"}}}`,
+		"f.json": `{"title": "f", "type": "object", "properties": {"next": {"$ref": "e.json"}}}`,
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]string{
+		"a.json": "Cyclic $ref: a.json -> b.json -> a.json",
+		"c.json": "Cyclic $ref: c.json -> d.json -> c.json",
+		"e.json": "",
+	}
+	for file, expected := range tests {
+		opts := Options{StructPrefix: "Json", BaseDir: dir}
+		schema, err := Load(filepath.Join(dir, file), opts)
+		if err == nil {
+			_, err = Generate(opts, schema)
+		}
+		if len(expected) == 0 && err != nil {
+			t.Errorf("%s: %v", file, err)
+		} else if len(expected) > 0 && (err == nil || err.Error() != expected) {
+			t.Errorf("%s: expected error %q, got %v", file, expected, err)
+		}
+	}
+}
+
+func TestInputDirectories(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a/user.json":   `{"title": "user", "type": "object", "properties": {"id": {"$ref": "common.json"}}}`,
+		"a/common.json": `{"type": "string"}`,
+		"b/order.json":  `{"title": "order", "type": "object", "properties": {"total": {"$ref": "common.json"}}}`,
+		"b/common.json": `{"type": "number"}`,
+	}
+	for name, src := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := Options{StructPrefix: "Json"}
+	var schemas []*JsonSchema
+	for _, file := range []string{"a/user.json", "b/order.json"} {
+		schema, err := Load(filepath.Join(dir, file), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schemas = append(schemas, schema)
+	}
+	out, err := Generate(opts, schemas...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`ID\s+string`).MatchString(out) || !regexp.MustCompile(`Total\s+float64`).MatchString(out) {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+}
+
+func TestDirectoryRefs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"tree/top.schema.json":   `{"title": "top", "type": "object", "properties": {"x": {"$ref": "sub/x.schema.json"}}}`,
+		"tree/sub/x.schema.json": `{"title": "x", "type": "object", "properties": {"y": {"$ref": "y.schema.json"}}}`,
+		"tree/sub/y.schema.json": `{"title": "y", "type": "object", "properties": {"name": {"type": "string"}}}`,
+	}
+	for name, src := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := Options{StructPrefix: "Json"}
+	var schemas []*JsonSchema
+	for _, file := range SortedKeys(files) {
+		schema, err := Load(filepath.Join(dir, file), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schemas = append(schemas, schema)
+	}
+	out, err := Generate(opts, schemas...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"type JsonTop struct", "X JsonX", "Y JsonY", "type JsonY struct"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestRelativeID(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.json":             `{"$id": "schemas/sub/", "title": "a", "type": "object", "properties": {"b": {"$ref": "b.json"}}}`,
+		"schemas/sub/b.json": `{"title": "b", "type": "object", "properties": {"name": {"type": "string"}}}`,
+	}
+	for name, src := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, opts := range []Options{{StructPrefix: "Json"}, {StructPrefix: "Json", BaseDir: dir}} {
+		path := "a.json"
+		if len(opts.BaseDir) == 0 {
+			path = filepath.Join(dir, path)
+		}
+		schema, err := Load(path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Generate(opts, schema)
+		if err != nil {
+			t.Errorf("BaseDir %q: %v", opts.BaseDir, err)
+		} else if !strings.Contains(out, "type JsonB struct") {
+			t.Errorf("BaseDir %q: unexpected output:\n%s", opts.BaseDir, out)
+		}
+	}
+}
+
+func TestConcurrentGenerate(t *testing.T) {
+	prefixes := []string{"Api", "Db", "Json", "Rpc"}
+	outputs := make([]string, len(prefixes))
+	errs := make([]error, len(prefixes))
+
+	var wg sync.WaitGroup
+	for i, prefix := range prefixes {
+		wg.Add(1)
+		go func(i int, prefix string) {
+			defer wg.Done()
+			outputs[i], errs[i] = generateFile(filepath.Join("testdata", "nested.schema.json"), Options{StructPrefix: prefix})
+		}(i, prefix)
+	}
+	wg.Wait()
+
+	for i, prefix := range prefixes {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		others := strings.Join(append(append([]string{}, prefixes[:i]...), prefixes[i+1:]...), "|")
+		if !strings.Contains(outputs[i], "type "+prefix) || regexp.MustCompile(`type (`+others+`)`).MatchString(outputs[i]) {
+			t.Errorf("Unexpected output for prefix %s:\n%s", prefix, outputs[i])
+		}
+	}
+}
+
+func TestMultipleInputs(t *testing.T) {
+	opts := Options{StructPrefix: "Json", BaseDir: "testdata"}
+	var schemas []*JsonSchema
+	for _, file := range []string{"multi_user.schema.json", "multi_order.schema.json"} {
+		schema, err := Load(file, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schemas = append(schemas, schema)
+	}
+
+	out, err := Generate(opts, schemas...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "type JsonUser struct") || !strings.Contains(out, "Buyer JsonUser") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+
+	conflict := &JsonSchema{
+		Title:      "user",
+		Type:       "object",
+		Properties: map[string]*JsonSchema{"id": {Type: "integer"}},
+	}
+	if _, err = Generate(opts, schemas[0], conflict); err == nil {
+		t.Error("Expected conflicting types across inputs to fail")
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	schema := func() *JsonSchema {
+		return &JsonSchema{
+			Title:      "broken",
+			Type:       "object",
+			Properties: map[string]*JsonSchema{"value": {CustomType: "not a type"}},
+		}
+	}
+
+	if _, err := Generate(Options{}, schema()); err == nil {
+		t.Error("Expected invalid source to fail formatting")
+	}
+
+	out, err := Generate(Options{NoFormat: true}, schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Value not a type") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+}
+
+func TestUnsupportedType(t *testing.T) {
+	schema := &JsonSchema{
+		Title: "event",
+		Type:  "object",
+		Properties: map[string]*JsonSchema{
+			"id":      {Type: "string"},
+			"payload": {Type: "blob"},
+			"parts":   {Type: "array"},
+		},
+	}
+
+	g := NewGenerator(Options{StructPrefix: "Json"})
+	out, err := g.Generate(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`Payload\s+interface{}`).MatchString(out) || !regexp.MustCompile(`Parts\s+interface{}`).MatchString(out) {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+	if len(g.Warnings) != 2 || !strings.HasPrefix(g.Warnings[1], "EventPayload: Unknown type string: blob") {
+		t.Errorf("Unexpected warnings: %q", g.Warnings)
+	}
+
+	if _, err = Generate(Options{Strict: true}, schema); err == nil {
+		t.Error("Expected unknown type to fail with Strict")
+	}
+}
+
+func TestStrictUntyped(t *testing.T) {
+	schema := &JsonSchema{
+		Title: "event",
+		Properties: map[string]*JsonSchema{
+			"payload": {},
+			"tags":    {Items: &JsonSchema{Type: "string"}},
+		},
+	}
+	if _, err := Generate(Options{StructPrefix: "Json", Strict: true}, schema); err == nil || !strings.Contains(err.Error(), "not inferred as object") {
+		t.Errorf("Expected missing object type to fail with Strict, got %v", err)
+	}
+
+	schema.Type = "object"
+	if _, err := Generate(Options{StructPrefix: "Json", Strict: true}, schema); err == nil || !strings.Contains(err.Error(), "EventTags: type is missing") {
+		t.Errorf("Expected missing array type to fail with Strict, got %v", err)
+	}
+
+	schema.Properties["tags"].Type = "array"
+	if _, err := Generate(Options{StructPrefix: "Json", Strict: true}, schema); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTitleCollision(t *testing.T) {
+	opts := Options{StructPrefix: "Json", BaseDir: "testdata"}
+	schema, err := Load("namespace_titles.schema.json", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGenerator(opts)
+	out, err := g.Generate(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`Metadata\s+JsonMetadata2 `).MatchString(out) || !strings.Contains(out, "type JsonMetadata2 struct") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+	if len(g.Warnings) != 1 || !strings.Contains(g.Warnings[0], "type JsonMetadata is already generated from another schema, using JsonMetadata2") {
+		t.Errorf("Unexpected warnings: %q", g.Warnings)
+	}
+
+	opts.Strict = true
+	if schema, err = Load("namespace_titles.schema.json", opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(opts, schema); err == nil || !strings.Contains(err.Error(), "Type JsonMetadata is generated from two different schemas") {
+		t.Errorf("Expected clashing titles to fail with Strict, got %v", err)
+	}
+}
+
+func TestEnumCollision(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"untitled.json": `{"type": "object", "properties": {
+			"order": {"title": "order", "type": "object", "properties": {"status": {"type": "string", "enum": ["open", "paid"]}}},
+			"user": {"title": "user", "type": "object", "properties": {"status": {"type": "integer", "enum": [1, 2]}}}
+		}}`,
+		"titled.json": `{"type": "object", "properties": {
+			"order": {"title": "order", "type": "object", "properties": {"status": {"title": "status", "type": "string", "enum": ["open", "paid"]}}},
+			"user": {"title": "user", "type": "object", "properties": {"status": {"title": "status", "type": "integer", "enum": [1, 2]}}}
+		}}`,
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := Options{StructPrefix: "Json", BaseDir: dir}
+	schema, err := Load("untitled.json", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Generate(opts, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"type JsonOrderStatus string", "OrderStatusOpen JsonOrderStatus", "type JsonUserStatus int64", "UserStatus1 JsonUserStatus"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Output does not contain %q:\n%s", expected, out)
+		}
+	}
+
+	if schema, err = Load("titled.json", opts); err != nil {
+		t.Fatal(err)
+	}
+	g := NewGenerator(opts)
+	if out, err = g.Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "type JsonStatus string") || !strings.Contains(out, "type JsonStatus2 int64") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+	if len(g.Warnings) != 1 || !strings.Contains(g.Warnings[0], "type JsonStatus is already generated from another schema, using JsonStatus2") {
+		t.Errorf("Unexpected warnings: %q", g.Warnings)
+	}
+
+	opts.Strict = true
+	if schema, err = Load("titled.json", opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(opts, schema); err == nil || !strings.Contains(err.Error(), "Type JsonStatus is generated from two different schemas") {
+		t.Errorf("Expected clashing enums to fail with Strict, got %v", err)
+	}
+}
+
+func TestProtoFieldErrors(t *testing.T) {
+	one, reserved := 1, 19000
+	for _, test := range []struct {
+		properties map[string]*JsonSchema
+		want       string
+	}{
+		{map[string]*JsonSchema{"a": {Type: "string", ProtoField: &one}, "b": {Type: "string", ProtoField: &one}}, "Duplicate x-proto-field 1: used by a and b"},
+		{map[string]*JsonSchema{"a": {Type: "string", ProtoField: &reserved}}, "Invalid x-proto-field 19000 for a"},
+	} {
+		schema := &JsonSchema{Title: "message", Type: "object", Properties: test.properties}
+		if _, err := Generate(Options{}, schema); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Expected %q, got %v", test.want, err)
+		}
+	}
+}
+
+func TestCatchAllOptions(t *testing.T) {
+	schema := &JsonSchema{
+		Title:                "record",
+		Type:                 "object",
+		Properties:           map[string]*JsonSchema{"id": {Type: "string"}},
+		AdditionalInterface:  map[string]interface{}{"type": "integer"},
+		AdditionalProperties: &JsonSchema{Type: "integer"},
+	}
+	out, err := Generate(Options{StructPrefix: "Json", CatchAll: true, CatchAllType: "map[string]interface{}"}, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`AdditionalProperties\s+map\[string\]interface{}`).MatchString(out) {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+
+	if _, err = Generate(Options{UniqueItems: "bogus"}, schema); err == nil || !strings.Contains(err.Error(), "Unsupported uniqueItems mode") {
+		t.Errorf("Expected unsupported uniqueItems mode to fail, got %v", err)
+	}
+	if _, err = Generate(Options{CatchAll: true, CatchAllType: "[]byte"}, schema); err == nil || !strings.Contains(err.Error(), "Unsupported catch-all type") {
+		t.Errorf("Expected unsupported catch-all type to fail, got %v", err)
+	}
+	if _, err = Generate(Options{CatchAll: true, CatchAllField: "extra"}, schema); err == nil || !strings.Contains(err.Error(), "Invalid catch-all field") {
+		t.Errorf("Expected unexported catch-all field to fail, got %v", err)
+	}
+}
+
+func TestResolvedSchema(t *testing.T) {
+	for _, test := range []struct {
+		file, want string
+	}{
+		{"extends.schema.json", `{"properties":{"email":{"type":"string"},"name":{"type":"string"},"salary":{"type":"number"}},"title":"employee","type":"object"}`},
+		{"recursive.schema.json", `"parent":{"$ref":"#"}`},
+	} {
+		g := NewGenerator(Options{BaseDir: "testdata"})
+		schema, err := g.Load(test.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved, err := g.ResolvedSchema(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.Marshal(resolved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), test.want) {
+			t.Errorf("%s: expected %s in:\n%s", test.file, test.want, out)
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	for _, file := range []string{"multi_order.schema.json", "id.schema.json", "deep_ref.schema.json", "embed_allof.schema.json"} {
+		opts := Options{StructPrefix: "Json", BaseDir: "testdata", Embed: true}
+		schema, err := Load(file, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Generate(opts, schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if schema, err = Load(file, opts); err != nil {
+			t.Fatal(err)
+		}
+		bundle, err := NewGenerator(opts).Bundle(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if regexp.MustCompile(`"\$ref":"[^#]`).Match(data) {
+			t.Errorf("%s: external ref left in bundle:\n%s", file, data)
+		}
+		bundled, err := Read(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Generate(Options{StructPrefix: "Json", Embed: true}, bundled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: bundle generates\n%s\nwant\n%s", file, got, want)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	var log strings.Builder
+	opts := Options{StructPrefix: "Json", BaseDir: "testdata", Log: &log}
+	schema, err := Load("multi_order.schema.json", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.Properties["meta"] = &JsonSchema{Type: "object"}
+	if _, err = Generate(opts, schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Loading ref multi_order.schema.json",
+		"Input 1: registered type JsonOrder",
+		"OrderMeta: object without properties or additionalProperties, using interface{}",
+	} {
+		if !strings.Contains(log.String(), line+"\n") {
+			t.Errorf("Expected %q in log:\n%s", line, log.String())
+		}
+	}
+}
+
+func TestNoComments(t *testing.T) {
+	out, err := generateFile("testdata/comments.schema.json", Options{NoComments: true, NoHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "//") {
+		t.Errorf("Unexpected comments:\n%s", out)
+	}
+}
+
+func TestGenTests(t *testing.T) {
+	schema := &JsonSchema{
+		Title:    "point",
+		Type:     "object",
+		Examples: []interface{}{map[string]interface{}{"x": 1.0}, map[string]interface{}{"x": "2"}},
+		Properties: map[string]*JsonSchema{
+			"x":     {Type: "integer"},
+			"shape": {OneOf: []*JsonSchema{{Title: "circle", Type: "object"}, {Title: "square", Type: "object"}}},
+		},
+	}
+
+	g := NewGenerator(Options{StructPrefix: "Json", GenTests: true})
+	if _, err := g.Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	out, err := g.TestSource("golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(out)
+	if !strings.Contains(src, "func TestJsonPointRoundTrip(t *testing.T) {") || !strings.Contains(src, `string(zero), "{\"x\":1}"`) {
+		t.Errorf("Missing round trip test:\n%s", src)
+	}
+	if !strings.Contains(src, "// The example {\"x\":\"2\"} is left out, since it doesn't match the schema.") || strings.Contains(src, `"{\"x\":\"2\"}"`) {
+		t.Errorf("Mismatched example not left out:\n%s", src)
+	}
+	if strings.Contains(src, "TestJsonShapeRoundTrip") {
+		t.Errorf("Unexpected test for interface type:\n%s", src)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl := `// {{.Name}} has {{len .Fields}} fields.
+type {{.Name}} struct {
+{{range .Fields}}{{.Name}} {{.Type}} {{tag (printf "%s db:%q" .Tag .Name)}}
+{{end}}}
+
+`
+	out, err := generateFile("testdata/simple.schema.json", Options{Template: tmpl, NoHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "// JsonPerson has 4 fields.") || !regexp.MustCompile("Name +string +`json:\"name\" db:\"Name\"`").MatchString(out) {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+
+	if _, err = generateFile("testdata/simple.schema.json", Options{Template: "{{.Name"}); err == nil || !strings.HasPrefix(err.Error(), "Invalid template:") {
+		t.Errorf("Expected invalid template error, got %v", err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	g := NewGenerator(Options{PackageName: "models", StructPrefix: "Json", BaseDir: "testdata"})
+	schema, err := g.Load("split.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := g.WriteSplit(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"constants.go":         {"package models", "type JsonUserProfileStatus string", "UserProfileStatusActive"},
+		"json_login_event.go":  {`"time"`, "type JsonLoginEvent struct"},
+		"json_user_profile.go": {"type JsonUserProfile struct"},
+	}
+	for file, contents := range expected {
+		src, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, content := range contents {
+			if !strings.Contains(string(src), content) {
+				t.Errorf("%s does not contain %s:\n%s", file, content, src)
+			}
+		}
+		if file != "json_login_event.go" && strings.Contains(string(src), "import") {
+			t.Errorf("%s has unused imports:\n%s", file, src)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "types.go")
+	if err := WriteFileAtomic(path, []byte("package a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected a new file to have mode 0644, got %v", info.Mode().Perm())
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("package b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file to keep mode 0600, got %v", info.Mode().Perm())
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "package b\n" {
+		t.Errorf("Unexpected contents %q (%v)", data, err)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	var a, b string
+	for i := 1; i <= 14; i++ {
+		a += strconv.Itoa(i) + "\n"
+		if i != 2 && i != 12 {
+			b += strconv.Itoa(i) + "\n"
+		}
+		if i == 4 {
+			b += "4b\n"
+		}
+	}
+	expected := `--- old
++++ new
+@@ -1,7 +1,7 @@
+ 1
+-2
+ 3
+ 4
++4b
+ 5
+ 6
+ 7
+@@ -9,6 +9,5 @@
+ 9
+ 10
+ 11
+-12
+ 13
+ 14
+`
+	if diff := UnifiedDiff("old", "new", a, b); diff != expected {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+	if diff := UnifiedDiff("old", "new", "", "x"); diff != "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n\\ No newline at end of file\n" {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+	if diff := UnifiedDiff("old", "new", a, a); diff != "" {
+		t.Errorf("Expected no diff, got:\n%s", diff)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"JsonUser":       "json_user",
+		"JsonAPIKey":     "json_api_key",
+		"JsonUserID":     "json_user_id",
+		"JsonInt64Set":   "json_int64_set",
+		"HTTPServer2Log": "http_server2_log",
+	}
+	for in, expected := range tests {
+		if out := SnakeCase(in); out != expected {
+			t.Errorf("SnakeCase(%q) = %q, expected %q", in, out, expected)
+		}
+	}
+}
+
+func TestCapitalize(t *testing.T) {
+	g := NewGenerator(Options{})
+	tests := map[string]string{
+		"first_name":   "FirstName",
+		"created-at":   "CreatedAt",
+		"user_id":      "UserID",
+		"user / admin": "UserAdmin",
+		"2fa-token":    "X2faToken",
+		"a.b+c":        "ABC",
+		"élan":         "Élan",
+		"名前":           "X名前",
+		"???":          "X3f3f3f",
+		"":             "",
+	}
+	for in, expected := range tests {
+		if out := g.Capitalize(in); out != expected {
+			t.Errorf("Capitalize(%q) = %q, expected %q", in, out, expected)
+		}
+	}
+}
+
+func TestCamelCollision(t *testing.T) {
+	opts := Options{StructPrefix: "Json", BaseDir: "testdata", Camel: true}
+	schema, err := Load("camel.schema.json", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGenerator(opts)
+	if _, err = g.Generate(schema); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Warnings) != 1 || g.Warnings[0] != "Account: properties dupKey and dup_key would both be encoded as dupKey, so dup_key keeps its key" {
+		t.Errorf("Unexpected warnings: %q", g.Warnings)
+	}
+
+	opts.Strict = true
+	if schema, err = Load("camel.schema.json", opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(opts, schema); err == nil || !strings.Contains(err.Error(), "would both be encoded as dupKey") {
+		t.Errorf("Expected colliding keys to fail with Strict, got %v", err)
+	}
+}
+
+func TestLowerCamel(t *testing.T) {
+	tests := map[string]string{
+		"first_name":       "firstName",
+		"last_login_at":    "lastLoginAt",
+		"double__under":    "doubleUnder",
+		"trailing_":        "trailing",
+		"_id":              "_id",
+		"__private_key":    "__privateKey",
+		"alreadyCamelCase": "alreadyCamelCase",
+		"_":                "_",
+	}
+	for in, expected := range tests {
+		if out := LowerCamel(in); out != expected {
+			t.Errorf("LowerCamel(%q) = %q, expected %q", in, out, expected)
+		}
+	}
+}
+
+func TestParseCustomType(t *testing.T) {
+	g := NewGenerator(Options{})
+	g.imports = make(map[string]bool)
+	tests := map[string]string{
+		"string":                        "string",
+		"time.Time":                     "time.Time",
+		"*github.com/google/uuid.UUID":  "*uuid.UUID",
+		"[]gopkg.in/yaml.v2.MapSlice":   "[]yaml.MapSlice",
+		"github.com/go-pg/pg/v10.Ident": "pg.Ident",
+	}
+	for in, expected := range tests {
+		if out, err := g.ParseCustomType(in); err != nil || out != expected {
+			t.Errorf("ParseCustomType(%q) = %q, %v, expected %q", in, out, err, expected)
+		}
+	}
+	if !g.imports["github.com/google/uuid"] || !g.imports["gopkg.in/yaml.v2"] {
+		t.Errorf("Missing imports: %v", g.imports)
+	}
+
+	for _, in := range []string{"github.com/google/uuid", "github.com/google/uuid.", ".UUID"} {
+		if _, err := g.ParseCustomType(in); err == nil {
+			t.Errorf("Expected ParseCustomType(%q) to fail", in)
+		}
+	}
+}
+
+func BenchmarkGenerateShared(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := generateFile(filepath.Join("testdata", "shared.schema.json"), Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestInvalidGoName(t *testing.T) {
+	for _, props := range []map[string]*JsonSchema{
+		{"id": {Type: "string", GoName: "id"}},
+		{"id": {Type: "string", GoName: "Customer ID"}},
+		{"a": {Type: "string", GoName: "Same"}, "b": {Type: "string", GoName: "Same"}},
+	} {
+		schema := &JsonSchema{Title: "user", Type: "object", Properties: props}
+		if _, err := Generate(Options{}, schema); err == nil || !strings.Contains(err.Error(), "x-go-name") {
+			t.Errorf("Expected x-go-name error for %v, got %v", props, err)
+		}
+	}
+}
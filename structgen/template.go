@@ -0,0 +1,101 @@
+package structgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplate is used to render each type when Options.Template is empty.
+const DefaultTemplate = `{{comment .Doc}}type {{.Name}} {{if .Fields}}struct {
+{{range .Fields}}{{comment .Doc}}{{with .Name}}{{.}} {{end}}{{.Type}}{{with .Tag}} {{tag .}}{{end}}
+{{end}}}{{else}}{{.Type}}{{end}}
+
+{{with .Consts}}{{.}}
+
+{{end}}{{.Methods}}`
+
+// TypeModel is the data passed to the type template for each generated type.
+type TypeModel struct {
+	Name    string
+	Doc     string
+	Type    string
+	Fields  []FieldModel
+	Consts  string
+	Methods string
+}
+
+// FieldModel is one field of a struct TypeModel. Name is empty for embedded
+// fields, and Tag is the struct tag without its quotes.
+type FieldModel struct {
+	Name string
+	Type string
+	Tag  string
+	Doc  string
+}
+
+var templateFuncs = template.FuncMap{
+	"comment": func(doc string) (out string) {
+		if len(doc) == 0 {
+			return
+		}
+		for _, line := range strings.Split(strings.TrimRight(doc, "\n"), "\n") {
+			out += strings.TrimRight("// "+line, " ") + "\n"
+		}
+		return
+	},
+	"tag": func(tag string) string {
+		return StructTag([]string{tag})
+	},
+}
+
+func (g *Generator) ParseTemplate() error {
+	text := g.Template
+	if len(text) == 0 {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("type").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("Invalid template: %v", err)
+	}
+	g.template = tmpl
+	return nil
+}
+
+func (g *Generator) TypeModel(name string) TypeModel {
+	model := TypeModel{Name: name, Type: g.types[name], Consts: g.consts[name]}
+	model.Methods = g.methods[name] + g.ValidateMethod(name)
+	if g.DeepCopy {
+		model.Methods += g.DeepCopyMethod(name)
+	}
+	if g.Equal {
+		model.Methods += g.EqualMethod(name)
+	}
+	if g.IsZero {
+		model.Methods += g.IsZeroMethod(name)
+	}
+
+	model.Doc = CommentText(g.Comment(name, g.docs[name]))
+	model.Fields = g.structs[g.types[name]]
+	return model
+}
+
+// CommentText returns the text of a comment from Comment, without the
+// slashes.
+func CommentText(comment string) (text string) {
+	for _, line := range strings.SplitAfter(comment, "\n") {
+		if len(line) > 0 {
+			text += strings.TrimPrefix(strings.TrimPrefix(line, "//"), " ")
+		}
+	}
+	return
+}
+
+func (g *Generator) TypeSource(name string) (string, error) {
+	var src bytes.Buffer
+	if err := g.template.Execute(&src, g.TypeModel(name)); err != nil {
+		return "", fmt.Errorf("Executing template for %s: %v", name, err)
+	}
+	return src.String(), nil
+}
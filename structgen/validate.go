@@ -0,0 +1,489 @@
+package structgen
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var orderedTypes = map[string]bool{
+	"float32": true,
+	"float64": true,
+	"int":     true,
+	"int32":   true,
+	"int64":   true,
+	"string":  true,
+}
+
+func (g *Generator) IsOrdered(typ string) bool {
+	return orderedTypes[typ] || orderedTypes[g.types[typ]]
+}
+
+func (g *Generator) BaseType(typ string) string {
+	if orderedTypes[g.types[typ]] {
+		return g.types[typ]
+	}
+	return typ
+}
+
+func (g *Generator) SetType(elem, path string) (string, error) {
+	src := "map[" + elem + "]struct{}"
+	name, err := g.ClaimName(ConstName(strings.TrimSuffix(strings.TrimPrefix(elem, g.StructPrefix), g.StructSuffix))+"Set", path, func(name string) bool {
+		return g.types[g.TypeName(name)] == src
+	})
+	if err != nil {
+		return "", err
+	}
+	name = g.TypeName(name)
+	if _, ok := g.types[name]; ok {
+		return name, nil
+	}
+
+	g.imports["encoding/json"] = true
+	g.imports["fmt"] = true
+	g.imports["sort"] = true
+	g.types[name] = src
+	g.docs[name] = "is a set of unique " + elem + " values, encoded as a JSON array."
+	g.methods[name] = `func (s ` + name + `) MarshalJSON() ([]byte, error) {
+	list := make([]` + elem + `, 0, len(s))
+	for v := range s {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return json.Marshal(list)
+}
+
+func (s *` + name + `) UnmarshalJSON(data []byte) error {
+	var list []` + elem + `
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = make(` + name + `, len(list))
+	for _, v := range list {
+		if _, ok := (*s)[v]; ok {
+			return fmt.Errorf("duplicate set item: %v", v)
+		}
+		(*s)[v] = struct{}{}
+	}
+	return nil
+}
+
+`
+	return name, nil
+}
+
+// ValidationChecks returns the checks of a value. The errors they return are
+// left as placeholders for Sentinels to name.
+func (g *Generator) ValidationChecks(expr, typ string, schema *JsonSchema) (checks []string, err error) {
+	switch g.UniqueItems {
+	case "set", "validate":
+		// Sets of unordered element types stay slices, so they are checked
+		// the same way as in validate mode.
+		if schema.UniqueItems && strings.HasPrefix(typ, "[") {
+			g.imports["fmt"] = true
+			g.imports["reflect"] = true
+			checks = append(checks, `for i := range `+expr+` {
+	for j := 0; j < i; j++ {
+		if reflect.DeepEqual(`+expr+`[i], `+expr+`[j]) {
+			return fmt.Errorf("%w: duplicate items at index %d and %d", `+SentinelPlaceholder("Duplicate")+`, j, i)
+		}
+	}
+}`)
+		}
+	}
+
+	if g.Validators {
+		value, guard, elem := expr, "", typ
+		if strings.HasPrefix(typ, "*") {
+			value, guard, elem = "*"+expr, expr+" != nil && ", typ[1:]
+		} else if optional, ok := g.OptionalElem(typ); ok {
+			value, guard, elem = expr+".Value", expr+".Valid && ", optional
+		}
+
+		if strings.HasPrefix(elem, "[]") {
+			if schema.MinItems != nil {
+				checks = append(checks, g.check(guard+"len("+value+") < "+strconv.Itoa(*schema.MinItems),
+					"ItemCount", "must have at least "+Items(*schema.MinItems)))
+			}
+			if schema.MaxItems != nil {
+				checks = append(checks, g.check(guard+"len("+value+") > "+strconv.Itoa(*schema.MaxItems),
+					"ItemCount", "must have at most "+Items(*schema.MaxItems)))
+			}
+		}
+
+		if strings.HasPrefix(elem, "[") && schema.Contains != nil {
+			contains, err := g.ContainsCheck(value, elem[strings.Index(elem, "]")+1:], schema)
+			if err != nil {
+				return nil, err
+			}
+			if len(contains) > 0 && len(guard) > 0 {
+				contains = "if " + strings.TrimSuffix(guard, " && ") + " {\n" + contains + "\n}"
+			}
+			if len(contains) > 0 {
+				checks = append(checks, contains)
+			}
+		}
+
+		if strings.HasPrefix(elem, "map[") && schema.PropertyNames != nil {
+			names := *schema.PropertyNames
+			if err = g.LoadRef(&names); err != nil {
+				return nil, err
+			}
+			if len(names.Pattern) > 0 {
+				if _, err = regexp.Compile(names.Pattern); err != nil {
+					return nil, fmt.Errorf("Invalid pattern %q: %v", names.Pattern, err)
+				}
+				name := "k"
+				if keyType := elem[len("map["):strings.Index(elem, "]")]; keyType != "string" {
+					name = "string(k)"
+				}
+				g.imports["fmt"] = true
+				checks = append(checks, `for k := range `+value+` {
+	if !`+g.PatternVar(names.Pattern)+`.MatchString(`+name+`) {
+		return fmt.Errorf(`+strconv.Quote("%w: key %q must match pattern "+strings.Replace(names.Pattern, "%", "%%", -1))+`, `+SentinelPlaceholder("Key")+`, k)
+	}
+}`)
+			}
+		}
+
+		base := g.BaseType(elem)
+		if base == "string" {
+			if base != elem {
+				value = "string(" + value + ")"
+			}
+			length := "utf8.RuneCountInString(" + value + ")"
+			if schema.MinLength != nil {
+				checks = append(checks, g.check(guard+length+" < "+strconv.Itoa(*schema.MinLength),
+					"Length", "length must be at least "+strconv.Itoa(*schema.MinLength)))
+			}
+			if schema.MaxLength != nil {
+				checks = append(checks, g.check(guard+length+" > "+strconv.Itoa(*schema.MaxLength),
+					"Length", "length must be at most "+strconv.Itoa(*schema.MaxLength)))
+			}
+			if schema.MinLength != nil || schema.MaxLength != nil {
+				g.imports["unicode/utf8"] = true
+			}
+			if len(schema.Pattern) > 0 {
+				if _, err = regexp.Compile(schema.Pattern); err != nil {
+					return nil, fmt.Errorf("Invalid pattern %q: %v", schema.Pattern, err)
+				}
+				checks = append(checks, g.check(guard+"!"+g.PatternVar(schema.Pattern)+".MatchString("+value+")",
+					"Pattern", "must match pattern "+schema.Pattern))
+			}
+		} else if orderedTypes[base] {
+			if schema.Minimum != nil {
+				checks = append(checks, g.check(guard+NumberExpr(value, base, *schema.Minimum)+" < "+NumberLiteral(*schema.Minimum),
+					"OutOfRange", "must be at least "+NumberLiteral(*schema.Minimum)))
+			}
+			if schema.Maximum != nil {
+				checks = append(checks, g.check(guard+NumberExpr(value, base, *schema.Maximum)+" > "+NumberLiteral(*schema.Maximum),
+					"OutOfRange", "must be at most "+NumberLiteral(*schema.Maximum)))
+			}
+			if schema.MultipleOf != nil {
+				checks = append(checks, g.check(guard+g.RemainderExpr(value, elem, *schema.MultipleOf),
+					"NotMultiple", "must be a multiple of "+NumberLiteral(*schema.MultipleOf)))
+			}
+		}
+	}
+
+	if _, ok := g.validations[typ]; ok {
+		checks = append(checks, `if err := `+expr+`.Validate(); err != nil {
+	return err
+}`)
+	} else if elem, ok := g.OptionalElem(typ); ok && len(g.validations[elem]) > 0 {
+		checks = append(checks, `if `+expr+`.Valid {
+	if err := `+expr+`.Value.Validate(); err != nil {
+		return err
+	}
+}`)
+	} else if _, ok := g.validations[strings.TrimPrefix(typ, "*")]; ok {
+		checks = append(checks, `if `+expr+` != nil {
+	if err := `+expr+`.Validate(); err != nil {
+		return err
+	}
+}`)
+	} else if nested := g.inline[schema.Origin()]; len(nested) > 0 {
+		// The checks of an inline struct refer to it as x, like in a
+		// Validate method, so x is shadowed by the field.
+		block := "x := " + expr + "\n" + strings.Join(nested, "\n") + "\n}"
+		if strings.HasPrefix(typ, "*") {
+			block = "if " + expr + " != nil {\nx := *" + expr + "\n" + strings.Join(nested, "\n") + "\n}"
+		} else if _, ok := g.OptionalElem(typ); ok {
+			block = "if " + expr + ".Valid {\nx := " + expr + ".Value\n" + strings.Join(nested, "\n") + "\n}"
+		} else {
+			block = "{\n" + block
+		}
+		checks = append(checks, block)
+	}
+	return
+}
+
+// jsonTypeAssertions are the Go types that decoding a JSON value of each type
+// into interface{} produces.
+var jsonTypeAssertions = map[string]string{
+	"array":   "[]interface{}",
+	"boolean": "bool",
+	"number":  "float64",
+	"object":  "map[string]interface{}",
+	"string":  "string",
+}
+
+// ContainsCheck returns a check that counts the items of value matching the
+// contains schema, and compares the count to minContains and maxContains.
+// Matching a whole schema would need a validator at runtime, so only const,
+// enum and type are matched; other contains schemas are not checked.
+func (g *Generator) ContainsCheck(value, item string, schema *JsonSchema) (string, error) {
+	contains := *schema.Contains
+	if err := g.LoadRef(&contains); err != nil {
+		return "", err
+	}
+
+	values := contains.Enum
+	if contains.Const != nil {
+		values = []interface{}{contains.Const}
+	}
+	var match string
+	if len(values) > 0 {
+		var conds []string
+		for _, v := range values {
+			if lit, ok := g.ItemLiteral(item, v); ok {
+				conds = append(conds, "v == "+lit)
+			}
+		}
+		if len(conds) == 0 {
+			g.Logf("%s: no contains value can be a %s item, so contains is not checked", value, item)
+			return "", nil
+		}
+		match = strings.Join(conds, " || ")
+	} else if t, ok := contains.Type.(string); ok && item == "interface{}" && len(jsonTypeAssertions[t]) > 0 && onlyType(&contains) {
+		match = "_, ok := v.(" + jsonTypeAssertions[t] + "); ok"
+	} else if contains.Type == nil || item == "interface{}" || !onlyType(&contains) {
+		g.Logf("%s: contains is only checked for const, enum and a type on its own", value)
+		return "", nil
+	}
+
+	// Every item of a typed array has the type that contains asks for, so
+	// only values need counting.
+	var checks []string
+	count := "len(" + value + ")"
+	if len(match) > 0 {
+		count = "n"
+		checks = append(checks, "n := 0\nfor _, v := range "+value+" {\nif "+match+" {\nn++\n}\n}")
+	}
+	minContains := 1
+	if schema.MinContains != nil {
+		minContains = *schema.MinContains
+	}
+	if minContains > 0 {
+		checks = append(checks, g.check(count+" < "+strconv.Itoa(minContains), "Contains", "must have at least "+Items(minContains)+" matching contains"))
+	}
+	if schema.MaxContains != nil {
+		checks = append(checks, g.check(count+" > "+strconv.Itoa(*schema.MaxContains), "Contains", "must have at most "+Items(*schema.MaxContains)+" matching contains"))
+	}
+	if len(checks) == 0 || count == "n" && len(checks) == 1 {
+		return "", nil
+	}
+	if count == "n" {
+		// The count is scoped to a block, so several fields can each have one.
+		return "{\n" + strings.Join(checks, "\n") + "\n}", nil
+	}
+	return strings.Join(checks, "\n"), nil
+}
+
+// onlyType reports whether js constrains nothing but the type of a value,
+// leaving aside annotations.
+func onlyType(js *JsonSchema) bool {
+	keys, _ := Keywords(js)
+	for _, key := range keys {
+		if key != "type" && key != "title" && key != "description" {
+			return false
+		}
+	}
+	return true
+}
+
+// ItemLiteral returns the literal that JSON value v decodes to as an item of
+// type item, for comparing items to it.
+func (g *Generator) ItemLiteral(item string, v interface{}) (string, bool) {
+	if item != "interface{}" {
+		return g.DefaultLiteral(item, v)
+	}
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return "float64(" + NumberLiteral(v) + ")", true
+	}
+	return "", false
+}
+
+func (g *Generator) check(cond, kind, message string) string {
+	g.imports["fmt"] = true
+	return "if " + cond + " {\n\treturn fmt.Errorf(" + strconv.Quote("%w: "+strings.Replace(message, "%", "%%", -1)) + ", " + SentinelPlaceholder(kind) + ")\n}"
+}
+
+var sentinelPattern = regexp.MustCompile("\x01[^\x01]+\x01")
+
+// sentinelPhrases describe each kind of check in the message of its sentinel
+// error, after the property key.
+var sentinelPhrases = map[string]string{
+	"Contains":    "has the wrong number of matching items",
+	"Duplicate":   "has duplicate items",
+	"ItemCount":   "has the wrong number of items",
+	"Key":         "has an invalid key",
+	"Length":      "has the wrong length",
+	"NotMultiple": "is not a valid multiple",
+	"OutOfRange":  "is out of range",
+	"Pattern":     "does not match its pattern",
+}
+
+func SentinelPlaceholder(kind string) string {
+	return "\x01" + kind + "\x01"
+}
+
+// NestSentinels returns the checks of a field of an inline struct with their
+// placeholders prefixed by the field and key, so that the type the struct is
+// a field of names the sentinels after the whole path, like
+// ErrJsonUserHomeCityLength.
+func NestSentinels(field, key string, checks []string) []string {
+	nested := make([]string, len(checks))
+	for i, check := range checks {
+		nested[i] = sentinelPattern.ReplaceAllStringFunc(check, func(match string) string {
+			subField, subKey, kind := sentinelParts(match)
+			return "\x01" + field + subField + "\x02" + key + subKey + "\x02" + kind + "\x01"
+		})
+	}
+	return nested
+}
+
+// sentinelParts splits a placeholder into the field and key path NestSentinels
+// added, if any, and the kind of check. The key path starts with a dot.
+func sentinelParts(placeholder string) (field, key, kind string) {
+	kind = placeholder[1 : len(placeholder)-1]
+	if parts := strings.Split(kind, "\x02"); len(parts) == 3 {
+		field, key, kind = parts[0], "."+parts[1], parts[2]
+	}
+	return
+}
+
+// Sentinels replaces the error placeholders in the checks of a field with
+// sentinel errors named after the type, field and kind of check, like
+// ErrJsonUserAgeOutOfRange, and returns their declarations.
+func (g *Generator) Sentinels(typeName, field, key string, checks []string) (decls []string) {
+	declared := make(map[string]bool)
+	for i, check := range checks {
+		checks[i] = sentinelPattern.ReplaceAllStringFunc(check, func(match string) string {
+			subField, subKey, kind := sentinelParts(match)
+			name := "Err" + typeName + field + subField + kind
+			if !declared[name] {
+				declared[name] = true
+				decls = append(decls, name+" = errors.New("+strconv.Quote(key+subKey+" "+sentinelPhrases[kind])+")")
+			}
+			return name
+		})
+	}
+	if len(decls) > 0 {
+		g.imports["errors"] = true
+	}
+	return
+}
+
+func Items(n int) string {
+	if n == 1 {
+		return "1 item"
+	}
+	return strconv.Itoa(n) + " items"
+}
+
+func NumberLiteral(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func NumberExpr(value, base string, bound float64) string {
+	if strings.HasPrefix(base, "int") && bound != math.Trunc(bound) {
+		return "float64(" + value + ")"
+	}
+	return value
+}
+
+// RemainderExpr returns a condition that is true when value is not a multiple
+// of divisor. Floats are compared with a tolerance relative to the value.
+func (g *Generator) RemainderExpr(value, typ string, divisor float64) string {
+	base := g.BaseType(typ)
+	if strings.HasPrefix(base, "int") && divisor == math.Trunc(divisor) {
+		return value + "%" + NumberLiteral(divisor) + " != 0"
+	}
+	epsilon := "1e-9"
+	if base == "float32" {
+		epsilon = "1e-6"
+	}
+	g.imports["math"] = true
+	if typ != "float64" {
+		value = "float64(" + value + ")"
+	}
+	return "math.Abs(math.Remainder(" + value + ", " + NumberLiteral(divisor) + ")) > " + epsilon + "*math.Abs(" + value + ")"
+}
+
+func (g *Generator) LimitConsts(name, typ string, schema *JsonSchema) (consts []string) {
+	typ, _ = g.OptionalElem(typ)
+	base := g.BaseType(strings.TrimPrefix(typ, "*"))
+	if !orderedTypes[base] || base == "string" {
+		return
+	}
+
+	bounds := []*float64{schema.Minimum, schema.Maximum}
+	for i, suffix := range []string{"Min", "Max"} {
+		if bounds[i] == nil {
+			continue
+		}
+		constType := base
+		if strings.HasPrefix(base, "int") && *bounds[i] != math.Trunc(*bounds[i]) {
+			constType = "float64"
+		}
+		consts = append(consts, name+suffix+" "+constType+" = "+NumberLiteral(*bounds[i]))
+	}
+	return
+}
+
+func (g *Generator) PatternVar(pattern string) string {
+	if name, ok := g.patterns[pattern]; ok {
+		return name
+	}
+	g.imports["regexp"] = true
+	name := "pattern" + strconv.Itoa(len(g.patterns)+1)
+	g.patterns[pattern] = name
+	return name
+}
+
+func (g *Generator) PatternVars() string {
+	if len(g.patterns) == 0 {
+		return ""
+	}
+
+	names := make(map[string]string)
+	for pattern, name := range g.patterns {
+		names[name] = pattern
+	}
+	src := "var (\n"
+	for _, name := range SortedKeys(names) {
+		src += name + " = regexp.MustCompile(" + strconv.Quote(names[name]) + ")\n"
+	}
+	return src + ")\n\n"
+}
+
+func (g *Generator) ValidateMethod(name string) string {
+	checks, ok := g.validations[name]
+	if !ok {
+		return ""
+	}
+
+	src := "func (x " + name + ") Validate() error {\n"
+	for _, check := range checks {
+		src += check + "\n"
+	}
+	return src + "return nil\n}\n\n"
+}